@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/spf13/viper"
 )
@@ -8,7 +11,6 @@ import (
 var (
 	VaultTokenField = field.StringField(
 		"vault-token",
-		field.WithRequired(true),
 		field.WithDescription("Vault Token"),
 	)
 	VaultHostField = field.StringField(
@@ -16,17 +18,148 @@ var (
 		field.WithRequired(true),
 		field.WithDescription("Vault address or Host. Ex. http://127.0.0.1:8200"),
 	)
+	VaultRoleIDField = field.StringField(
+		"vault-role-id",
+		field.WithDescription("AppRole role ID used to log in to Vault instead of a static token"),
+	)
+	VaultSecretIDField = field.StringField(
+		"vault-secret-id",
+		field.WithDescription("AppRole secret ID used together with the role ID"),
+	)
+	VaultAppRoleMountField = field.StringField(
+		"vault-approle-mount",
+		field.WithDescription("Mount path of the AppRole auth method used to log in"),
+		field.WithDefaultValue(client.DefaultAppRoleMount),
+	)
+	VaultKubernetesRoleField = field.StringField(
+		"vault-kubernetes-role",
+		field.WithDescription("Kubernetes auth method role used to log in to Vault with the pod service account token"),
+	)
+	VaultKubernetesMountField = field.StringField(
+		"vault-kubernetes-mount",
+		field.WithDescription("Mount path of the Kubernetes auth method used to log in"),
+		field.WithDefaultValue(client.DefaultKubernetesMount),
+	)
+	VaultKubernetesJWTPathField = field.StringField(
+		"vault-kubernetes-jwt-path",
+		field.WithDescription("Path of the service account token file, read again on every login"),
+		field.WithDefaultValue(client.DefaultKubernetesJWTPath),
+	)
+	VaultJWTRoleField = field.StringField(
+		"vault-jwt-role",
+		field.WithDescription("JWT auth method role used to log in to Vault with a workload identity token"),
+	)
+	VaultJWTMountField = field.StringField(
+		"vault-jwt-mount",
+		field.WithDescription("Mount path of the JWT auth method used to log in"),
+		field.WithDefaultValue(client.DefaultJWTMount),
+	)
+	VaultJWTPathField = field.StringField(
+		"vault-jwt-path",
+		field.WithDescription("Path of the file holding the workload identity token"),
+	)
+	VaultJWTEnvField = field.StringField(
+		"vault-jwt-env",
+		field.WithDescription("Name of the environment variable holding the workload identity token"),
+	)
+	VaultClientCertField = field.StringField(
+		"vault-client-cert",
+		field.WithDescription("Path of the PEM client certificate presented to Vault on every request"),
+	)
+	VaultClientKeyField = field.StringField(
+		"vault-client-key",
+		field.WithDescription("Path of the PEM private key of the client certificate"),
+	)
+	VaultCACertField = field.StringField(
+		"vault-ca-cert",
+		field.WithDescription("Path of the PEM bundle of the certificate authorities that sign the certificate of Vault, in place of the system ones"),
+	)
+	VaultCertRoleField = field.StringField(
+		"vault-cert-role",
+		field.WithDescription("TLS certificate auth method role matched against the client certificate. "+
+			"Without it Vault matches the certificate against every role of the mount"),
+	)
+	VaultCertMountField = field.StringField(
+		"vault-cert-mount",
+		field.WithDescription("Mount path of the TLS certificate auth method used to log in"),
+		field.WithDefaultValue(client.DefaultCertMount),
+	)
+	VaultNamespaceField = field.StringField(
+		"vault-namespace",
+		field.WithDescription("Vault Enterprise namespace to sync, ex. bu1/team-a. Defaults to the root namespace"),
+	)
+	VaultDiscoverNamespacesField = field.BoolField(
+		"vault-discover-namespaces",
+		field.WithDescription("Also sync every namespace nested under the configured one"),
+	)
+	VaultMaxRetriesField = field.IntField(
+		"vault-max-retries",
+		field.WithDescription("How many times a read is retried while Vault is sealed, on a standby node, rate limited or electing a leader"),
+		field.WithDefaultValue(client.DefaultMaxRetries),
+	)
+	VaultSetupMountsField = field.BoolField(
+		"vault-setup-mounts",
+		field.WithDescription("Enable the approle and userpass auth methods and the kv secrets engine if they are missing, "+
+			"then exit without syncing"),
+	)
 
-	FieldRelationships = []field.SchemaFieldRelationship{}
+	FieldRelationships = []field.SchemaFieldRelationship{
+		// A client certificate given without any other credential logs in through the TLS certificate auth method.
+		field.FieldsAtLeastOneUsed(VaultTokenField, VaultRoleIDField, VaultKubernetesRoleField, VaultJWTRoleField, VaultCertRoleField, VaultClientCertField),
+		field.FieldsMutuallyExclusive(VaultTokenField, VaultRoleIDField, VaultKubernetesRoleField, VaultJWTRoleField, VaultCertRoleField),
+		field.FieldsDependentOn(
+			[]field.SchemaField{VaultSecretIDField},
+			[]field.SchemaField{VaultRoleIDField},
+		),
+		field.FieldsMutuallyExclusive(VaultJWTPathField, VaultJWTEnvField),
+		field.FieldsDependentOn(
+			[]field.SchemaField{VaultJWTPathField, VaultJWTEnvField},
+			[]field.SchemaField{VaultJWTRoleField},
+		),
+		field.FieldsRequiredTogether(VaultClientCertField, VaultClientKeyField),
+		field.FieldsDependentOn(
+			[]field.SchemaField{VaultCertRoleField},
+			[]field.SchemaField{VaultClientCertField, VaultClientKeyField},
+		),
+	}
 
 	// ConfigurationFields defines the external configuration required for the connector to run.
 	ConfigurationFields = []field.SchemaField{
 		VaultTokenField,
 		VaultHostField,
+		VaultRoleIDField,
+		VaultSecretIDField,
+		VaultAppRoleMountField,
+		VaultKubernetesRoleField,
+		VaultKubernetesMountField,
+		VaultKubernetesJWTPathField,
+		VaultJWTRoleField,
+		VaultJWTMountField,
+		VaultJWTPathField,
+		VaultJWTEnvField,
+		VaultClientCertField,
+		VaultClientKeyField,
+		VaultCACertField,
+		VaultCertRoleField,
+		VaultCertMountField,
+		VaultNamespaceField,
+		VaultDiscoverNamespacesField,
+		VaultMaxRetriesField,
+		VaultSetupMountsField,
 	}
-	Configurations = field.NewConfiguration(ConfigurationFields)
+	Configurations = field.NewConfiguration(ConfigurationFields, FieldRelationships...)
 )
 
 func ValidateConfig(v *viper.Viper) error {
+	if v.GetString(VaultJWTRoleField.GetName()) != "" &&
+		v.GetString(VaultJWTPathField.GetName()) == "" &&
+		v.GetString(VaultJWTEnvField.GetName()) == "" {
+		return fmt.Errorf("either %s or %s must be set when using %s",
+			VaultJWTPathField.GetName(),
+			VaultJWTEnvField.GetName(),
+			VaultJWTRoleField.GetName(),
+		)
+	}
+
 	return nil
 }
@@ -14,7 +14,127 @@ func TestConfigs(t *testing.T) {
 	)
 
 	testCases := []test.TestCase{
-		// Add test cases here.
+		{
+			Configs: map[string]string{},
+			IsValid: false,
+			Message: "empty config",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":  "http://127.0.0.1:8200",
+				"vault-token": "token",
+			},
+			IsValid: true,
+			Message: "static token",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":      "http://127.0.0.1:8200",
+				"vault-role-id":   "role-id",
+				"vault-secret-id": "secret-id",
+			},
+			IsValid: true,
+			Message: "approle login",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":            "http://127.0.0.1:8200",
+				"vault-kubernetes-role": "baton",
+			},
+			IsValid: true,
+			Message: "kubernetes login",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":     "http://127.0.0.1:8200",
+				"vault-jwt-role": "ci",
+				"vault-jwt-env":  "CI_JOB_JWT",
+			},
+			IsValid: true,
+			Message: "jwt login from env",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":     "http://127.0.0.1:8200",
+				"vault-jwt-role": "ci",
+			},
+			IsValid: false,
+			Message: "jwt login without token source",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":     "http://127.0.0.1:8200",
+				"vault-jwt-role": "ci",
+				"vault-jwt-path": "/var/run/jwt",
+				"vault-jwt-env":  "CI_JOB_JWT",
+			},
+			IsValid: false,
+			Message: "jwt login with two token sources",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":        "https://127.0.0.1:8200",
+				"vault-cert-role":   "baton",
+				"vault-client-cert": "/etc/baton/client.pem",
+				"vault-client-key":  "/etc/baton/client-key.pem",
+			},
+			IsValid: true,
+			Message: "certificate login",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":        "https://127.0.0.1:8200",
+				"vault-client-cert": "/etc/baton/client.pem",
+				"vault-client-key":  "/etc/baton/client-key.pem",
+				"vault-ca-cert":     "/etc/baton/ca.pem",
+			},
+			IsValid: true,
+			Message: "certificate login matching any role",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":        "https://127.0.0.1:8200",
+				"vault-token":       "token",
+				"vault-client-cert": "/etc/baton/client.pem",
+			},
+			IsValid: false,
+			Message: "client certificate without key",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":      "https://127.0.0.1:8200",
+				"vault-cert-role": "baton",
+			},
+			IsValid: false,
+			Message: "certificate login without client certificate",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":    "http://127.0.0.1:8200",
+				"vault-token":   "token",
+				"vault-role-id": "role-id",
+			},
+			IsValid: false,
+			Message: "token and approle together",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":            "http://127.0.0.1:8200",
+				"vault-role-id":         "role-id",
+				"vault-kubernetes-role": "baton",
+			},
+			IsValid: false,
+			Message: "approle and kubernetes together",
+		},
+		{
+			Configs: map[string]string{
+				"vault-host":      "http://127.0.0.1:8200",
+				"vault-token":     "token",
+				"vault-secret-id": "secret-id",
+			},
+			IsValid: false,
+			Message: "secret id without role id",
+		},
 	}
 
 	test.ExerciseTestCases(t, configurationSchema, ValidateConfig, testCases)
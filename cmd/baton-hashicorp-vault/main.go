@@ -9,8 +9,10 @@ import (
 	"github.com/conductorone/baton-hashicorp-vault/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/conductorone/baton-sdk/pkg/types"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
@@ -22,7 +24,7 @@ var (
 
 func main() {
 	ctx := context.Background()
-	_, cmd, err := config.DefineConfiguration(
+	v, cmd, err := config.DefineConfiguration(
 		ctx,
 		connectorName,
 		getConnector,
@@ -34,6 +36,21 @@ func main() {
 	}
 
 	cmd.Version = version
+	runConnector := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		err := v.BindPFlags(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		// Setting up the mounts is a one-shot mode, it never starts a sync or the connector service.
+		if v.GetBool(VaultSetupMountsField.GetName()) {
+			return setupMounts(ctx, v)
+		}
+
+		return runConnector(cmd, args)
+	}
+
 	err = cmd.Execute()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -41,21 +58,114 @@ func main() {
 	}
 }
 
+// setupMounts enables the approle and userpass auth methods and the kv secrets engine when they are missing, then
+// returns without syncing.
+func setupMounts(ctx context.Context, cfg *viper.Viper) error {
+	err := field.Validate(Configurations, cfg)
+	if err != nil {
+		return err
+	}
+
+	hcpClient, err := newHCPClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	hcpClient.WithReadOnly(false)
+	hcpClient, err = client.New(ctx, hcpClient)
+	if err != nil {
+		return err
+	}
+
+	return hcpClient.EnableStores(ctx)
+}
+
+// newHCPClient configures a client from the configuration, it is read-only until the caller says otherwise.
+func newHCPClient(cfg *viper.Viper) (*client.HCPClient, error) {
+	var (
+		hcpClient    = client.NewClient()
+		token        = cfg.GetString(VaultTokenField.GetName())
+		host         = cfg.GetString(VaultHostField.GetName())
+		roleID       = cfg.GetString(VaultRoleIDField.GetName())
+		secretID     = cfg.GetString(VaultSecretIDField.GetName())
+		appRoleMount = cfg.GetString(VaultAppRoleMountField.GetName())
+		k8sRole      = cfg.GetString(VaultKubernetesRoleField.GetName())
+		k8sMount     = cfg.GetString(VaultKubernetesMountField.GetName())
+		k8sJWTPath   = cfg.GetString(VaultKubernetesJWTPathField.GetName())
+		jwtRole      = cfg.GetString(VaultJWTRoleField.GetName())
+		jwtMount     = cfg.GetString(VaultJWTMountField.GetName())
+		jwtPath      = cfg.GetString(VaultJWTPathField.GetName())
+		jwtEnv       = cfg.GetString(VaultJWTEnvField.GetName())
+		clientCert   = cfg.GetString(VaultClientCertField.GetName())
+		clientKey    = cfg.GetString(VaultClientKeyField.GetName())
+		caCert       = cfg.GetString(VaultCACertField.GetName())
+		certRole     = cfg.GetString(VaultCertRoleField.GetName())
+		certMount    = cfg.GetString(VaultCertMountField.GetName())
+		namespace    = cfg.GetString(VaultNamespaceField.GetName())
+		discoverNs   = cfg.GetBool(VaultDiscoverNamespacesField.GetName())
+		maxRetries   = cfg.GetInt(VaultMaxRetriesField.GetName())
+	)
+	err := ValidateConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = hcpClient.WithAddress(host)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientCert != "" {
+		err = hcpClient.WithClientCertificate(clientCert, clientKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if caCert != "" {
+		err = hcpClient.WithCACertificate(caCert)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case roleID != "":
+		hcpClient.WithAppRole(appRoleMount, roleID, secretID)
+	case k8sRole != "":
+		hcpClient.WithKubernetes(k8sMount, k8sRole, k8sJWTPath)
+	case jwtRole != "":
+		hcpClient.WithJWT(jwtMount, jwtRole, jwtPath, jwtEnv)
+	case certRole != "", clientCert != "" && token == "":
+		hcpClient.WithCertLogin(certMount, certRole)
+	default:
+		hcpClient.WithBearerToken(token)
+	}
+
+	hcpClient.WithNamespace(namespace)
+	hcpClient.WithNamespaceDiscovery(discoverNs)
+	hcpClient.WithMaxRetries(maxRetries)
+	return hcpClient, nil
+}
+
 func getConnector(ctx context.Context, cfg *viper.Viper) (types.ConnectorServer, error) {
 	var (
-		hcpClient = client.NewClient()
-		token     = cfg.GetString(VaultTokenField.GetName())
-		host      = cfg.GetString(VaultHostField.GetName())
+		host         = cfg.GetString(VaultHostField.GetName())
+		provisioning = cfg.GetBool("provisioning")
 	)
 	l := ctxzap.Extract(ctx)
-	err := hcpClient.WithAddress(host)
+	if cfg.GetBool(VaultSetupMountsField.GetName()) {
+		return nil, fmt.Errorf("--%s sets up the mounts and exits, run the connector without it", VaultSetupMountsField.GetName())
+	}
+
+	hcpClient, err := newHCPClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	hcpClient.WithBearerToken(token)
+	// Vault is only written to when provisioning.
+	hcpClient.WithReadOnly(!provisioning)
 	cb, err := connector.New(ctx,
-		token,
 		host,
 		hcpClient,
 	)
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupMounts(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		writes []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == http.MethodPost {
+			writes = append(writes, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var data any
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			data = map[string]any{"ttl": 0}
+		case "/v1/sys/auth":
+			data = map[string]any{"userpass/": map[string]any{"type": "userpass"}}
+		case "/v1/sys/mounts":
+			data = map[string]any{}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := viper.New()
+	cfg.Set(VaultHostField.GetName(), server.URL)
+	cfg.Set(VaultTokenField.GetName(), "token")
+	cfg.Set(VaultSetupMountsField.GetName(), true)
+
+	require.Nil(t, setupMounts(context.Background(), cfg))
+	require.Equal(t, []string{"/v1/sys/auth/approle", "/v1/sys/mounts/kv"}, writes)
+
+	// A sync is never started with the setup mode on, it would enable the mounts again on every start.
+	_, err := getConnector(context.Background(), cfg)
+	require.ErrorContains(t, err, VaultSetupMountsField.GetName())
+}
@@ -0,0 +1,352 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+const (
+	ACLPoliciesEndpoint = "v1/sys/policies/acl"
+	RootPolicy          = "root"
+)
+
+// legacyPolicies maps the deprecated policy field of a path stanza to the capabilities it stands for.
+var legacyPolicies = map[string][]string{
+	"deny":  {CapabilityDeny},
+	"read":  {"read", "list"},
+	"write": {"create", "read", "update", "delete", "list"},
+	"sudo":  {"create", "read", "update", "delete", "list", "sudo"},
+}
+
+// ACLRule is a path stanza of an ACL policy.
+// https://developer.hashicorp.com/vault/docs/concepts/policies#policy-syntax
+type ACLRule struct {
+	// Path is the path as written in the policy, it may hold "+" segments and end with "*".
+	Path               string
+	Capabilities       []string
+	AllowedParameters  map[string][]interface{}
+	DeniedParameters   map[string][]interface{}
+	RequiredParameters []string
+	MinWrappingTTL     time.Duration
+	MaxWrappingTTL     time.Duration
+}
+
+// aclPathHCL is the raw content of a path stanza.
+type aclPathHCL struct {
+	Policy             string                   `hcl:"policy"`
+	Capabilities       []string                 `hcl:"capabilities"`
+	AllowedParameters  map[string][]interface{} `hcl:"allowed_parameters"`
+	DeniedParameters   map[string][]interface{} `hcl:"denied_parameters"`
+	RequiredParameters []string                 `hcl:"required_parameters"`
+	MinWrappingTTL     interface{}              `hcl:"min_wrapping_ttl"`
+	MaxWrappingTTL     interface{}              `hcl:"max_wrapping_ttl"`
+}
+
+// GetACLPolicy. Read the rules of an ACL policy.
+// https://developer.hashicorp.com/vault/api-docs/system/policies#read-acl-policy
+func (h *HCPClient) GetACLPolicy(ctx context.Context, name string) (*ACLPolicyAPIData, error) {
+	policyUrl, err := url.JoinPath(h.baseUrl, ACLPoliciesEndpoint, name)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(policyUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *ACLPolicyAPIData
+	err = h.getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// WriteACLPolicy. Create or replace an ACL policy with the given HCL rules.
+// https://developer.hashicorp.com/vault/api-docs/system/policies#create-update-acl-policy
+func (h *HCPClient) WriteACLPolicy(ctx context.Context, name, rules string) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, ACLPoliciesEndpoint, name)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyACLPolicy{
+		Policy: rules,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ParseACLPolicy parses the HCL rules of an ACL policy. Stanzas repeating a path are merged,
+// deny overriding any other capability, and deprecated policy fields are turned into capabilities.
+func ParseACLPolicy(rules string) ([]ACLRule, error) {
+	root, err := hcl.ParseString(rules)
+	if err != nil {
+		return nil, fmt.Errorf("hcp-client: error parsing policy: %w", err)
+	}
+
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("hcp-client: error parsing policy: root should be an object")
+	}
+
+	var aclRules []ACLRule
+	for _, item := range list.Filter("path").Items {
+		if len(item.Keys) == 0 {
+			return nil, fmt.Errorf("hcp-client: error parsing policy: path stanza at %s has no path", item.Pos())
+		}
+
+		path, ok := item.Keys[0].Token.Value().(string)
+		if !ok {
+			return nil, fmt.Errorf("hcp-client: error parsing policy: invalid path at %s", item.Pos())
+		}
+
+		var raw aclPathHCL
+		if err := hcl.DecodeObject(&raw, item.Val); err != nil {
+			return nil, fmt.Errorf("hcp-client: error parsing policy path %s: %w", path, err)
+		}
+
+		rule, err := raw.normalize(strings.TrimPrefix(path, "/"))
+		if err != nil {
+			return nil, err
+		}
+
+		pos := slices.IndexFunc(aclRules, func(r ACLRule) bool {
+			return r.Path == rule.Path
+		})
+		if pos == -1 {
+			aclRules = append(aclRules, rule)
+			continue
+		}
+
+		aclRules[pos].merge(rule)
+	}
+
+	return aclRules, nil
+}
+
+func (raw *aclPathHCL) normalize(path string) (ACLRule, error) {
+	rule := ACLRule{
+		Path:               path,
+		AllowedParameters:  raw.AllowedParameters,
+		DeniedParameters:   raw.DeniedParameters,
+		RequiredParameters: raw.RequiredParameters,
+	}
+
+	capabilities := slices.Clone(legacyPolicies[strings.ToLower(raw.Policy)])
+	if raw.Policy != "" && capabilities == nil {
+		return ACLRule{}, fmt.Errorf("hcp-client: invalid policy %q on path %s", raw.Policy, path)
+	}
+
+	for _, capability := range raw.Capabilities {
+		capabilities = append(capabilities, strings.ToLower(capability))
+	}
+	rule.Capabilities = normalizeCapabilities(capabilities)
+
+	var err error
+	rule.MinWrappingTTL, err = parseDurationSecond(raw.MinWrappingTTL)
+	if err != nil {
+		return ACLRule{}, fmt.Errorf("hcp-client: invalid min_wrapping_ttl on path %s: %w", path, err)
+	}
+
+	rule.MaxWrappingTTL, err = parseDurationSecond(raw.MaxWrappingTTL)
+	if err != nil {
+		return ACLRule{}, fmt.Errorf("hcp-client: invalid max_wrapping_ttl on path %s: %w", path, err)
+	}
+
+	return rule, nil
+}
+
+// merge adds the rules of a stanza repeating the path of r.
+func (r *ACLRule) merge(other ACLRule) {
+	r.Capabilities = normalizeCapabilities(append(r.Capabilities, other.Capabilities...))
+	r.RequiredParameters = append(r.RequiredParameters, other.RequiredParameters...)
+	r.AllowedParameters = mergeParameters(r.AllowedParameters, other.AllowedParameters)
+	r.DeniedParameters = mergeParameters(r.DeniedParameters, other.DeniedParameters)
+	if other.MinWrappingTTL != 0 && (r.MinWrappingTTL == 0 || other.MinWrappingTTL < r.MinWrappingTTL) {
+		r.MinWrappingTTL = other.MinWrappingTTL
+	}
+
+	if other.MaxWrappingTTL > r.MaxWrappingTTL {
+		r.MaxWrappingTTL = other.MaxWrappingTTL
+	}
+}
+
+// normalizeCapabilities sorts and deduplicates capabilities. A deny hides every other capability.
+func normalizeCapabilities(capabilities []string) []string {
+	if slices.Contains(capabilities, CapabilityDeny) {
+		return []string{CapabilityDeny}
+	}
+
+	capabilities = slices.Clone(capabilities)
+	slices.Sort(capabilities)
+	return slices.Compact(capabilities)
+}
+
+// mergeParameters adds the parameters of b to a. The values of b are copied, never shared with a.
+func mergeParameters(a, b map[string][]interface{}) map[string][]interface{} {
+	if len(b) == 0 {
+		return a
+	}
+
+	if a == nil {
+		a = make(map[string][]interface{}, len(b))
+	}
+
+	for key, values := range b {
+		a[key] = append(slices.Clone(a[key]), values...)
+	}
+
+	return a
+}
+
+// parseDurationSecond reads a TTL written as a number of seconds or as a Go duration, ex. "90s".
+func parseDurationSecond(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case int64:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Duration(seconds) * time.Second, nil
+		}
+
+		return time.ParseDuration(v)
+	}
+
+	return 0, fmt.Errorf("unsupported duration %v", value)
+}
+
+// Matches reports whether the rule applies to a request path, "+" matching one path segment
+// and a trailing "*" any suffix.
+func (r *ACLRule) Matches(path string) bool {
+	glob := strings.HasSuffix(r.Path, "*")
+	pattern := strings.TrimSuffix(r.Path, "*")
+	patternSegments := strings.Split(pattern, "/")
+	if !slices.Contains(patternSegments, "+") {
+		if glob {
+			return strings.HasPrefix(path, pattern)
+		}
+
+		return path == pattern
+	}
+
+	pathSegments := strings.Split(path, "/")
+	if len(pathSegments) < len(patternSegments) || (!glob && len(pathSegments) != len(patternSegments)) {
+		return false
+	}
+
+	for i, segment := range patternSegments {
+		switch {
+		case segment == "+":
+		case glob && i == len(patternSegments)-1:
+			if !strings.HasPrefix(pathSegments[i], segment) {
+				return false
+			}
+		case pathSegments[i] != segment:
+			return false
+		}
+	}
+
+	return true
+}
+
+// isExact reports whether the rule names a single path, without any "+" segment or "*" suffix.
+func (r *ACLRule) isExact() bool {
+	return !strings.HasSuffix(r.Path, "*") && !slices.Contains(strings.Split(r.Path, "/"), "+")
+}
+
+// firstWildcard returns the position of the first "+" segment or of the trailing "*".
+func (r *ACLRule) firstWildcard() int {
+	pos := len(r.Path)
+	if strings.HasSuffix(r.Path, "*") {
+		pos = len(r.Path) - 1
+	}
+
+	if r.Path == "+" || strings.HasPrefix(r.Path, "+/") {
+		return 0
+	}
+
+	if i := strings.Index(r.Path, "/+/"); i != -1 && i+1 < pos {
+		pos = i + 1
+	}
+
+	if strings.HasSuffix(r.Path, "/+") && len(r.Path)-1 < pos {
+		pos = len(r.Path) - 1
+	}
+
+	return pos
+}
+
+// higherPriority reports whether Vault prefers r over other when both match a path.
+// https://developer.hashicorp.com/vault/docs/concepts/policies#priority-matching
+func (r *ACLRule) higherPriority(other *ACLRule) bool {
+	if r.firstWildcard() != other.firstWildcard() {
+		return r.firstWildcard() > other.firstWildcard()
+	}
+
+	rGlob, otherGlob := strings.HasSuffix(r.Path, "*"), strings.HasSuffix(other.Path, "*")
+	if rGlob != otherGlob {
+		return !rGlob
+	}
+
+	rPlus, otherPlus := strings.Count(r.Path, "+"), strings.Count(other.Path, "+")
+	if rPlus != otherPlus {
+		return rPlus < otherPlus
+	}
+
+	if len(r.Path) != len(other.Path) {
+		return len(r.Path) > len(other.Path)
+	}
+
+	return r.Path > other.Path
+}
+
+// MatchACLRule returns the rule Vault applies to a request path, nil when no rule matches.
+// An exact path wins over any pattern, patterns are ranked by Vault's priority matching.
+func MatchACLRule(rules []ACLRule, path string) *ACLRule {
+	var match *ACLRule
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(path) {
+			continue
+		}
+
+		if rule.isExact() {
+			return rule
+		}
+
+		if match == nil || rule.higherPriority(match) {
+			match = rule
+		}
+	}
+
+	return match
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseACLPolicy(t *testing.T) {
+	rules, err := ParseACLPolicy(`
+path "secret/data/+/db*" {
+  capabilities = ["read", "list"]
+  allowed_parameters = {
+    "env" = ["prod", "dev"]
+  }
+  required_parameters = ["env"]
+  min_wrapping_ttl = "1m"
+  max_wrapping_ttl = 3600
+}
+
+path "secret/data/+/db*" {
+  capabilities = ["update", "read"]
+}
+
+path "sys/policy" {
+  policy = "write"
+}
+
+path "/secret/data/admin" {
+  capabilities = ["read", "deny"]
+  denied_parameters = {
+    "*" = []
+  }
+}
+`)
+	require.Nil(t, err)
+	require.Len(t, rules, 3)
+
+	require.Equal(t, "secret/data/+/db*", rules[0].Path)
+	require.Equal(t, []string{"list", "read", "update"}, rules[0].Capabilities)
+	require.Equal(t, []interface{}{"prod", "dev"}, rules[0].AllowedParameters["env"])
+	require.Equal(t, []string{"env"}, rules[0].RequiredParameters)
+	require.Equal(t, time.Minute, rules[0].MinWrappingTTL)
+	require.Equal(t, time.Hour, rules[0].MaxWrappingTTL)
+
+	require.Equal(t, "sys/policy", rules[1].Path)
+	require.Equal(t, []string{"create", "delete", "list", "read", "update"}, rules[1].Capabilities)
+
+	require.Equal(t, "secret/data/admin", rules[2].Path)
+	require.Equal(t, []string{CapabilityDeny}, rules[2].Capabilities)
+	require.Contains(t, rules[2].DeniedParameters, "*")
+}
+
+func TestParseACLPolicyInvalid(t *testing.T) {
+	_, err := ParseACLPolicy(`path "secret/*" { policy = "everything" }`)
+	require.NotNil(t, err)
+
+	_, err = ParseACLPolicy(`path "secret/*" {`)
+	require.NotNil(t, err)
+}
+
+func TestMatchACLRule(t *testing.T) {
+	rules, err := ParseACLPolicy(`
+path "secret/*" { capabilities = ["read", "list"] }
+path "secret/prod/*" { capabilities = ["deny"] }
+path "secret/+/db" { capabilities = ["update"] }
+path "secret/+/db*" { capabilities = ["create"] }
+path "secret/dev/db" { capabilities = ["delete"] }
+path "secret/+/+/api" { capabilities = ["sudo"] }
+`)
+	require.Nil(t, err)
+
+	testCases := []struct {
+		path string
+		rule string
+	}{
+		{"secret/app", "secret/*"},
+		{"secret/prod/db", "secret/prod/*"},
+		{"secret/test/db", "secret/+/db"},
+		{"secret/test/db2", "secret/*"},
+		{"secret/dev/db", "secret/dev/db"},
+		{"secret/a/b/api", "secret/+/+/api"},
+		{"secret/prod/b/api", "secret/prod/*"},
+		{"kv/app", ""},
+	}
+	for _, tc := range testCases {
+		rule := MatchACLRule(rules, tc.path)
+		if tc.rule == "" {
+			require.Nil(t, rule, tc.path)
+			continue
+		}
+
+		require.NotNil(t, rule, tc.path)
+		require.Equal(t, tc.rule, rule.Path, tc.path)
+	}
+}
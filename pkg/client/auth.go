@@ -0,0 +1,399 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultAppRoleMount      = "approle"
+	DefaultKubernetesMount   = "kubernetes"
+	DefaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	DefaultJWTMount          = "jwt"
+	DefaultCertMount         = "cert"
+	DefaultUserpassMount     = "userpass"
+	TokenLookupSelfEndpoint  = "v1/auth/token/lookup-self"
+	TokenRenewSelfEndpoint   = "v1/auth/token/renew-self"
+	// tokenExpiryMargin is how long before the lease ends a token is considered expired.
+	tokenExpiryMargin = 30 * time.Second
+)
+
+// loginRequest returns the login endpoint of an auth method and the payload to send to it.
+type loginRequest func() (string, any, error)
+
+// WithAppRole makes the client log in through the AppRole auth method instead of using a static token.
+// https://developer.hashicorp.com/vault/api-docs/auth/approle#login-with-approle
+func (h *HCPClient) WithAppRole(mount, roleID, secretID string) {
+	if mount == "" {
+		mount = DefaultAppRoleMount
+	}
+
+	h.auth.login = func() (string, any, error) {
+		return loginEndpoint(mount), bodyAppRoleLogin{
+			RoleID:   roleID,
+			SecretID: secretID,
+		}, nil
+	}
+}
+
+// WithKubernetes makes the client log in through the Kubernetes auth method with a service account token.
+// The token file is read again on every login so projected tokens can be rotated by the kubelet.
+// https://developer.hashicorp.com/vault/api-docs/auth/kubernetes#login
+func (h *HCPClient) WithKubernetes(mount, role, jwtPath string) {
+	if mount == "" {
+		mount = DefaultKubernetesMount
+	}
+
+	if jwtPath == "" {
+		jwtPath = DefaultKubernetesJWTPath
+	}
+
+	h.auth.login = func() (string, any, error) {
+		jwt, err := readJWTFile(jwtPath)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return loginEndpoint(mount), bodyRoleJWTLogin{
+			Role: role,
+			JWT:  jwt,
+		}, nil
+	}
+}
+
+// WithJWT makes the client log in through the JWT/OIDC auth method with a workload identity token.
+// The token is read from jwtPath when set, otherwise from the jwtEnv environment variable, on every login.
+// https://developer.hashicorp.com/vault/api-docs/auth/jwt#jwt-login
+func (h *HCPClient) WithJWT(mount, role, jwtPath, jwtEnv string) {
+	if mount == "" {
+		mount = DefaultJWTMount
+	}
+
+	h.auth.login = func() (string, any, error) {
+		var (
+			jwt string
+			err error
+		)
+		if jwtPath != "" {
+			jwt, err = readJWTFile(jwtPath)
+			if err != nil {
+				return "", nil, err
+			}
+		} else {
+			jwt = strings.TrimSpace(os.Getenv(jwtEnv))
+			if jwt == "" {
+				return "", nil, fmt.Errorf("hcp-client: environment variable %s holds no jwt", jwtEnv)
+			}
+		}
+
+		return loginEndpoint(mount), bodyRoleJWTLogin{
+			Role: role,
+			JWT:  jwt,
+		}, nil
+	}
+}
+
+// WithClientCertificate loads a client certificate and key that are presented on every request.
+func (h *HCPClient) WithClientCertificate(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("hcp-client: error loading client certificate: %w", err)
+	}
+
+	h.transportTLSConfig().Certificates = []tls.Certificate{cert}
+	return nil
+}
+
+// WithCACertificate loads a PEM bundle of the certificate authorities trusted to sign the certificate of
+// Vault's listeners, in place of the system ones.
+func (h *HCPClient) WithCACertificate(caFile string) error {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("hcp-client: error reading ca certificate %s: %w", caFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("hcp-client: no certificate found in %s", caFile)
+	}
+
+	h.transportTLSConfig().RootCAs = pool
+	return nil
+}
+
+func (h *HCPClient) transportTLSConfig() *tls.Config {
+	if h.tlsConfig == nil {
+		h.tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return h.tlsConfig
+}
+
+// WithCertLogin makes the client log in through the TLS certificate auth method with the client certificate.
+// When role is empty Vault matches the certificate against every role of the mount.
+// https://developer.hashicorp.com/vault/api-docs/auth/cert#login-with-tls-certificate-method
+func (h *HCPClient) WithCertLogin(mount, role string) {
+	if mount == "" {
+		mount = DefaultCertMount
+	}
+
+	h.auth.login = func() (string, any, error) {
+		if h.tlsConfig == nil || len(h.tlsConfig.Certificates) == 0 {
+			return "", nil, fmt.Errorf("hcp-client: certificate login requires a client certificate")
+		}
+
+		return loginEndpoint(mount), bodyCertLogin{
+			Name: role,
+		}, nil
+	}
+}
+
+func readJWTFile(jwtPath string) (string, error) {
+	jwt, err := os.ReadFile(jwtPath)
+	if err != nil {
+		return "", fmt.Errorf("hcp-client: error reading jwt file %s: %w", jwtPath, err)
+	}
+
+	return strings.TrimSpace(string(jwt)), nil
+}
+
+func loginEndpoint(mount string) string {
+	return fmt.Sprintf("v1/auth/%s/login", mount)
+}
+
+// getToken returns the current client token. Tokens about to expire are renewed, or replaced by logging in
+// again for login based auth methods. It is safe to call concurrently.
+func (h *HCPClient) getToken(ctx context.Context) (string, error) {
+	h.auth.mu.Lock()
+	defer h.auth.mu.Unlock()
+
+	switch {
+	case h.auth.login != nil:
+		if h.auth.bearerToken == "" || h.auth.expiresSoon() {
+			if err := h.authenticate(ctx); err != nil {
+				return "", err
+			}
+		}
+	case h.auth.expiresSoon():
+		if !h.auth.renewable {
+			return "", status.Errorf(codes.Unauthenticated,
+				"hcp-client: vault token expires at %s and is not renewable, use a longer lived token or a login based auth method",
+				h.auth.expiresAt.Format(time.RFC3339),
+			)
+		}
+
+		if err := h.renewSelf(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	return h.auth.bearerToken, nil
+}
+
+// recoverToken handles a 403 answered to a request sent with token and reports whether the request should be
+// sent again. Vault answers 403 both to a missing capability and to an unknown token, so the token is only
+// replaced, by logging in again, once lookup-self shows Vault no longer knows it. Concurrent requests rejected
+// with the same token wait for a single login and then retry with its token.
+func (h *HCPClient) recoverToken(ctx context.Context, token string) (bool, error) {
+	h.auth.mu.Lock()
+	defer h.auth.mu.Unlock()
+
+	if h.auth.login == nil || token == "" {
+		return false, nil
+	}
+
+	if h.auth.bearerToken != token {
+		// Another request already logged in again.
+		return true, nil
+	}
+
+	valid, err := h.isTokenValid(ctx, token)
+	if err != nil {
+		return false, err
+	}
+
+	if valid {
+		return false, nil
+	}
+
+	if err := h.authenticate(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// isTokenValid looks the token up and reports whether Vault still knows it.
+func (h *HCPClient) isTokenValid(ctx context.Context, token string) (bool, error) {
+	var res TokenLookupAPIData
+	err := h.sendAuthRequest(ctx, http.MethodGet, TokenLookupSelfEndpoint, token, nil, &res)
+	if IsPermissionDenied(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// authenticate exchanges the configured credentials for a client token and caches it with its lease.
+// Callers must hold auth.mu.
+func (h *HCPClient) authenticate(ctx context.Context) error {
+	l := ctxzap.Extract(ctx)
+	loginPath, body, err := h.auth.login()
+	if err != nil {
+		return err
+	}
+
+	var res loginAPIData
+	err = h.sendAuthRequest(ctx, http.MethodPost, loginPath, "", body, &res)
+	if err != nil {
+		return fmt.Errorf("hcp-client: login to %s failed: %w", loginPath, err)
+	}
+
+	if res.Auth.ClientToken == "" {
+		return fmt.Errorf("hcp-client: login to %s returned no client token", loginPath)
+	}
+
+	h.auth.bearerToken = res.Auth.ClientToken
+	h.auth.setLease(res.Auth.LeaseDuration, res.Auth.Renewable)
+	l.Debug("logged in to vault",
+		zap.String("path", loginPath),
+		zap.String("accessor", res.Auth.Accessor),
+		zap.Time("expires_at", h.auth.expiresAt),
+	)
+
+	return nil
+}
+
+// renewSelf extends the lease of the current token. Callers must hold auth.mu.
+// https://developer.hashicorp.com/vault/api-docs/auth/token#renew-a-token-self
+func (h *HCPClient) renewSelf(ctx context.Context) error {
+	l := ctxzap.Extract(ctx)
+	var res loginAPIData
+	err := h.sendAuthRequest(ctx, http.MethodPost, TokenRenewSelfEndpoint, h.auth.bearerToken, nil, &res)
+	if err != nil {
+		return fmt.Errorf("hcp-client: error renewing vault token: %w", err)
+	}
+
+	h.auth.setLease(res.Auth.LeaseDuration, res.Auth.Renewable)
+	if h.auth.expiresSoon() {
+		// The token reached its max TTL, renewing it again would not extend it any further.
+		h.auth.renewable = false
+	}
+
+	l.Debug("renewed vault token", zap.Time("expires_at", h.auth.expiresAt))
+
+	return nil
+}
+
+// LookupSelf returns information about the token used by the client.
+// https://developer.hashicorp.com/vault/api-docs/auth/token#lookup-a-token-self
+func (h *HCPClient) LookupSelf(ctx context.Context) (*TokenLookupAPIData, error) {
+	token, err := h.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res TokenLookupAPIData
+	err = h.sendAuthRequest(ctx, http.MethodGet, TokenLookupSelfEndpoint, token, nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// initToken reads the lease of a static token at startup so it can be renewed before it runs out.
+func (h *HCPClient) initToken(ctx context.Context) error {
+	l := ctxzap.Extract(ctx)
+	lookup, err := h.LookupSelf(ctx)
+	if err != nil {
+		return fmt.Errorf("hcp-client: error looking up vault token: %w", err)
+	}
+
+	h.auth.mu.Lock()
+	defer h.auth.mu.Unlock()
+
+	h.auth.setLease(lookup.Data.TTL, lookup.Data.Renewable)
+	if !h.auth.expiresAt.IsZero() && !h.auth.renewable {
+		l.Warn("vault token is not renewable, requests made after it expires will fail",
+			zap.Time("expires_at", h.auth.expiresAt),
+		)
+	}
+
+	return nil
+}
+
+// sendAuthRequest sends a token management request. Unlike doRequest it is allowed in read-only mode
+// since logging in and renewing never modify what the connector syncs. Logging in, renewing and looking up
+// the token can all be sent again, so they are retried while Vault is unavailable whatever their method.
+// They skip the response cache, a cached lookup would report a revoked token as valid.
+func (h *HCPClient) sendAuthRequest(ctx context.Context, method, path, token string, body, res any) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, path)
+	if err != nil {
+		return err
+	}
+
+	uri, err := url.Parse(endpointUrl)
+	if err != nil {
+		return err
+	}
+
+	options := []uhttp.RequestOption{uhttp.WithJSONBody(body)}
+	if token != "" {
+		options = append(options, uhttp.WithHeader(AuthHeaderName, token))
+	}
+
+	// Auth methods and tokens live in the configured namespace whatever namespace is being synced.
+	if h.rootNamespace != "" {
+		options = append(options, uhttp.WithHeader(NamespaceHeaderName, h.rootNamespace))
+	}
+
+	resp, err := h.retry(ctx, func() (*http.Response, error) {
+		req, err := h.httpClient.NewRequest(ctx, method, uri, options...)
+		if err != nil {
+			return nil, err
+		}
+
+		return h.sendUncached(req, res)
+	})
+	if err != nil && resp != nil && resp.StatusCode >= http.StatusBadRequest {
+		return getError(method, resp)
+	}
+
+	return err
+}
+
+func (a *auth) setLease(leaseDuration int, renewable bool) {
+	a.renewable = renewable
+	a.expiresAt = time.Time{}
+	if leaseDuration > 0 {
+		a.expiresAt = time.Now().Add(time.Duration(leaseDuration) * time.Second)
+	}
+}
+
+func (a *auth) expiresSoon() bool {
+	if a.expiresAt.IsZero() {
+		return false
+	}
+
+	return time.Now().Add(tokenExpiryMargin).After(a.expiresAt)
+}
@@ -0,0 +1,322 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeVault answers the token endpoints and the ACL policy endpoint of Vault.
+type fakeVault struct {
+	logins     atomic.Int32
+	renewals   atomic.Int32
+	lookups    atomic.Int32
+	leaseTTL   int
+	renewable  bool
+	revoked    sync.Map
+	allowRead  func(token string) bool
+	loginToken func(login int32) string
+	// unavailable is how many of the next requests are answered 503, as a sealed or standby node would.
+	unavailable atomic.Int32
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get(AuthHeaderName)
+	if _, revoked := f.revoked.Load(token); revoked && r.URL.Path != "/v1/auth/approle/login" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	if f.unavailable.Add(-1) >= 0 {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		login := f.logins.Add(1)
+		writeJSON(w, map[string]any{"auth": map[string]any{
+			"client_token":   f.loginToken(login),
+			"lease_duration": f.leaseTTL,
+			"renewable":      f.renewable,
+		}})
+	case "/v1/auth/token/lookup-self":
+		f.lookups.Add(1)
+		writeJSON(w, map[string]any{"data": map[string]any{"ttl": f.leaseTTL, "renewable": f.renewable}})
+	case "/v1/auth/token/renew-self":
+		f.renewals.Add(1)
+		writeJSON(w, map[string]any{"auth": map[string]any{"client_token": token, "lease_duration": 3600, "renewable": true}})
+	case "/v1/sys/policies/acl/default":
+		if f.allowRead != nil && !f.allowRead(token) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		writeJSON(w, map[string]any{"data": map[string]any{"name": "default", "policy": ""}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestClient(t *testing.T, vault *fakeVault, configure func(*HCPClient)) *HCPClient {
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+
+	cli := NewClient()
+	require.Nil(t, cli.WithAddress(server.URL))
+	cli.WithMaxRetries(0)
+	configure(cli)
+
+	cli, err := New(context.Background(), cli)
+	require.Nil(t, err)
+
+	return cli
+}
+
+func sequentialTokens(login int32) string {
+	return fmt.Sprintf("token-%d", login)
+}
+
+func TestExpiresSoon(t *testing.T) {
+	testCases := []struct {
+		expiresAt time.Time
+		soon      bool
+	}{
+		{time.Time{}, false},
+		{time.Now().Add(time.Hour), false},
+		{time.Now().Add(tokenExpiryMargin / 2), true},
+		{time.Now().Add(-time.Minute), true},
+	}
+	for _, tc := range testCases {
+		a := &auth{expiresAt: tc.expiresAt}
+		require.Equal(t, tc.soon, a.expiresSoon(), tc.expiresAt)
+	}
+}
+
+func TestGetTokenLogsInOnce(t *testing.T) {
+	vault := &fakeVault{leaseTTL: 3600, renewable: true, loginToken: sequentialTokens}
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithAppRole("", "role", "secret")
+	})
+
+	for i := 0; i < 3; i++ {
+		token, err := cli.getToken(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, "token-1", token)
+	}
+
+	require.EqualValues(t, 1, vault.logins.Load())
+}
+
+func TestGetTokenLogsInAgainBeforeExpiry(t *testing.T) {
+	vault := &fakeVault{leaseTTL: 10, renewable: true, loginToken: sequentialTokens}
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithAppRole("", "role", "secret")
+	})
+
+	token, err := cli.getToken(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, "token-2", token)
+	require.EqualValues(t, 2, vault.logins.Load())
+}
+
+func TestGetTokenRenewsStaticToken(t *testing.T) {
+	vault := &fakeVault{leaseTTL: 10, renewable: true}
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithBearerToken("static")
+	})
+
+	for i := 0; i < 2; i++ {
+		token, err := cli.getToken(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, "static", token)
+	}
+
+	require.EqualValues(t, 1, vault.renewals.Load())
+}
+
+func TestGetTokenExpiredStaticToken(t *testing.T) {
+	vault := &fakeVault{leaseTTL: 10, renewable: false}
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithBearerToken("static")
+	})
+
+	_, err := cli.getToken(context.Background())
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.EqualValues(t, 0, vault.renewals.Load())
+}
+
+func TestForbiddenKeepsValidToken(t *testing.T) {
+	vault := &fakeVault{
+		leaseTTL:   3600,
+		renewable:  true,
+		loginToken: sequentialTokens,
+		allowRead:  func(string) bool { return false },
+	}
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithAppRole("", "role", "secret")
+	})
+
+	_, err := cli.GetACLPolicy(context.Background(), "default")
+	require.True(t, IsPermissionDenied(err))
+	require.EqualValues(t, 1, vault.logins.Load())
+	require.EqualValues(t, 1, vault.lookups.Load())
+}
+
+func TestForbiddenLogsInAgainOnce(t *testing.T) {
+	vault := &fakeVault{leaseTTL: 3600, renewable: true, loginToken: sequentialTokens}
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithAppRole("", "role", "secret")
+	})
+
+	// The first token is revoked before its lease ends.
+	vault.revoked.Store("token-1", true)
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cli.Uncached().GetACLPolicy(context.Background(), "default")
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Nil(t, err)
+	}
+
+	require.EqualValues(t, 2, vault.logins.Load())
+}
+
+func TestAuthRequestsRetryWhileUnavailable(t *testing.T) {
+	vault := &fakeVault{leaseTTL: 10, renewable: true, loginToken: sequentialTokens}
+	vault.unavailable.Store(2)
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithAppRole("", "role", "secret")
+		c.WithMaxRetries(2)
+	})
+	require.EqualValues(t, 1, vault.logins.Load())
+
+	// The token is about to expire, a 503 answered to the renewal is retried instead of failing the sync.
+	cli.auth.login = nil
+	vault.unavailable.Store(1)
+	_, err := cli.getToken(context.Background())
+	require.Nil(t, err)
+	require.EqualValues(t, 1, vault.renewals.Load())
+}
+
+func TestAuthRequestErrors(t *testing.T) {
+	vault := &fakeVault{leaseTTL: 3600, renewable: true, loginToken: sequentialTokens}
+	cli := newTestClient(t, vault, func(c *HCPClient) {
+		c.WithBearerToken("static")
+	})
+
+	// Looking the token up again skips the response cache, so a revoked token is not reported valid.
+	_, err := cli.LookupSelf(context.Background())
+	require.Nil(t, err)
+	vault.revoked.Store("static", true)
+	_, err = cli.LookupSelf(context.Background())
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	vault.unavailable.Store(1)
+	cli.WithMaxRetries(0)
+	cli.WithAppRole("", "role", "secret")
+	cli.auth.mu.Lock()
+	err = cli.authenticate(context.Background())
+	cli.auth.mu.Unlock()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+// writePEM writes a PEM block to a file of the test's temporary directory and returns its path.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.Nil(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func TestCertLoginWithoutRole(t *testing.T) {
+	var loginBody map[string]any
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/cert/login" || len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&loginBody)
+		writeJSON(w, map[string]any{"auth": map[string]any{"client_token": "token-1", "lease_duration": 3600}})
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "baton"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	cli := NewClient()
+	require.Nil(t, cli.WithAddress(server.URL))
+	cli.WithMaxRetries(0)
+	require.Nil(t, cli.WithClientCertificate(
+		writePEM(t, "client.pem", "CERTIFICATE", certDER),
+		writePEM(t, "client-key.pem", "EC PRIVATE KEY", keyDER),
+	))
+	// The listener certificate is signed by a private authority, only trusted through the bundle.
+	require.Nil(t, cli.WithCACertificate(writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)))
+	cli.WithCertLogin("", "")
+
+	cli, err = New(context.Background(), cli)
+	require.Nil(t, err)
+
+	token, err := cli.getToken(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, "token-1", token)
+	// Without a role Vault matches the certificate against every role of the mount.
+	require.NotContains(t, loginBody, "name")
+}
+
+func TestCACertificateWithoutCertificate(t *testing.T) {
+	cli := NewClient()
+	require.NotNil(t, cli.WithCACertificate(writePEM(t, "ca.pem", "PRIVATE KEY", []byte("key"))))
+}
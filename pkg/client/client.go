@@ -1,50 +1,73 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	AuthHeaderName      = "X-Vault-Token"
 	DefaultAddress      = "http://127.0.0.1:8200"
-	UsersEndpoint       = "v1/auth/userpass/users"
-	RolesEndpoint       = "v1/auth/approle/role"
 	KvEndpoint          = "v1/kv"
-	SecEndpoint         = "v1/secret/metadata"
 	AuthMethodsEndpoint = "v1/sys/auth"
 	GroupsEndpoint      = "v1/identity/group/id"
 	EntityEndpoint      = "v1/identity/entity/id"
 	policiesEndpoint    = "v1/sys/policy"
+	MountsEndpoint      = "v1/sys/mounts"
 	ApproleAuthEndpoint = "v1/sys/auth/approle"
 	UserAuthEndpoint    = "v1/sys/auth/userpass"
 	KvAuthEndpoint      = "v1/sys/mounts/kv"
 	MethodList          = "LIST"
-	approleType         = "approle"
-	userpassType        = "userpass"
+	ApproleType         = "approle"
+	UserpassType        = "userpass"
 	kvType              = "kv"
-	StatusBadRequest    = "400 Bad Request"
+	approleMount        = "approle/"
+	userpassMount       = "userpass/"
+	kvMount             = "kv/"
 )
 
-var listEndpoints = []string{KvEndpoint, SecEndpoint}
+// ErrReadOnly is returned by any request that would modify Vault while the client is read-only.
+var ErrReadOnly = errors.New("hcp-client: client is in read-only mode")
 
 type HCPClient struct {
 	httpClient *uhttp.BaseHttpClient
 	auth       *auth
 	baseUrl    string
+	readOnly   bool
+	tlsConfig  *tls.Config
+	// rootNamespace is the configured namespace, namespace the one requests are sent to.
+	rootNamespace      string
+	namespace          string
+	discoverNamespaces bool
+	maxRetries         int
+	rateLimit          *rateLimitState
+	namespaceCache     *namespaceCache
+	// syncs counts the syncs started with StartSync, it is shared by every copy of the client.
+	syncs *atomic.Uint64
+	// uncached makes GET requests skip the response cache of the http client.
+	uncached bool
 }
 
 type CustomErr struct {
-	Errors []string `json:"errors"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
 }
 
 func NewClient() *HCPClient {
@@ -54,6 +77,8 @@ func NewClient() *HCPClient {
 		auth: &auth{
 			bearerToken: "",
 		},
+		readOnly:   true,
+		maxRetries: DefaultMaxRetries,
 	}
 }
 
@@ -61,6 +86,46 @@ func (h *HCPClient) WithBearerToken(apiToken string) {
 	h.auth.bearerToken = apiToken
 }
 
+// WithReadOnly controls whether the client may send requests that modify Vault.
+// Clients are read-only by default.
+func (h *HCPClient) WithReadOnly(readOnly bool) {
+	h.readOnly = readOnly
+}
+
+// ReadOnly reports whether the client refuses every request that would modify Vault.
+func (h *HCPClient) ReadOnly() bool {
+	return h.readOnly
+}
+
+// Uncached returns a client whose GET requests always reach Vault. Reads made before a write must use it,
+// the response cache would otherwise hand back what Vault returned before an earlier write.
+func (h *HCPClient) Uncached() *HCPClient {
+	cli := *h
+	cli.uncached = true
+	return &cli
+}
+
+// StartSync marks the start of a sync, the connector calls it when it is validated before every sync. It empties
+// the namespace cache, and the caches kept by callers compare SyncGeneration with the generation they were filled
+// at to drop what they kept during a previous sync.
+func (h *HCPClient) StartSync() {
+	if h.namespaceCache != nil {
+		h.namespaceCache.reset()
+	}
+	if h.syncs != nil {
+		h.syncs.Add(1)
+	}
+}
+
+// SyncGeneration returns the number of syncs started with StartSync.
+func (h *HCPClient) SyncGeneration() uint64 {
+	if h.syncs == nil {
+		return 0
+	}
+
+	return h.syncs.Load()
+}
+
 func (h *HCPClient) WithAddress(host string) error {
 	if !isValidUrl(host) {
 		return fmt.Errorf("host is not valid")
@@ -70,21 +135,19 @@ func (h *HCPClient) WithAddress(host string) error {
 	return nil
 }
 
-func (h *HCPClient) getToken() string {
-	return h.auth.bearerToken
-}
-
 func isValidUrl(baseUrl string) bool {
 	u, err := url.Parse(baseUrl)
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
 func New(ctx context.Context, hcpClient *HCPClient) (*HCPClient, error) {
-	var (
-		clientToken = hcpClient.getToken()
-		baseUrl     = DefaultAddress
-	)
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+	var baseUrl = DefaultAddress
+	options := []uhttp.Option{uhttp.WithLogger(true, ctxzap.Extract(ctx))}
+	if hcpClient.tlsConfig != nil {
+		options = append(options, uhttp.WithTLSClientConfig(hcpClient.tlsConfig))
+	}
+
+	httpClient, err := uhttp.NewClient(ctx, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -107,11 +170,26 @@ func New(ctx context.Context, hcpClient *HCPClient) (*HCPClient, error) {
 		httpClient: cli,
 		baseUrl:    baseUrl,
 		auth: &auth{
-			bearerToken: clientToken,
+			bearerToken: hcpClient.auth.bearerToken,
+			login:       hcpClient.auth.login,
 		},
+		readOnly:           hcpClient.readOnly,
+		tlsConfig:          hcpClient.tlsConfig,
+		rootNamespace:      hcpClient.rootNamespace,
+		namespace:          hcpClient.rootNamespace,
+		discoverNamespaces: hcpClient.discoverNamespaces,
+		maxRetries:         hcpClient.maxRetries,
+		rateLimit:          &rateLimitState{},
+		namespaceCache:     &namespaceCache{},
+		syncs:              &atomic.Uint64{},
+	}
+
+	if hcp.auth.login != nil {
+		_, err = hcp.getToken(ctx)
+	} else if hcp.auth.bearerToken != "" {
+		err = hcp.initToken(ctx)
 	}
 
-	err = enableStores(ctx, &hcp)
 	if err != nil {
 		return nil, err
 	}
@@ -119,51 +197,51 @@ func New(ctx context.Context, hcpClient *HCPClient) (*HCPClient, error) {
 	return &hcp, nil
 }
 
-func enableStores(ctx context.Context, hcpClient *HCPClient) error {
-	isAuthError, err := hcpClient.CheckAuthenticationMethod(ctx, ApproleAuthEndpoint)
-	if err != nil {
-		if isAuthError && strings.Contains(err.Error(), StatusBadRequest) {
-			err = hcpClient.EnableAuthMethod(ctx, ApproleAuthEndpoint, BodyEnableAuth{
-				Type: approleType,
-			})
-			if err != nil {
-				return err
-			}
-		}
+// EnableStores enables the approle and userpass auth methods and the kv secrets engine when they are missing.
+// It is only meant to bootstrap a cluster and is never called during a regular sync.
+func (h *HCPClient) EnableStores(ctx context.Context) error {
+	if h.readOnly {
+		return ErrReadOnly
+	}
+
+	return enableStores(ctx, h.Uncached())
+}
 
-		if !isAuthError {
+func enableStores(ctx context.Context, hcpClient *HCPClient) error {
+	authMethods := []struct {
+		mount    string
+		endpoint string
+		body     BodyEnableAuth
+	}{
+		{approleMount, ApproleAuthEndpoint, BodyEnableAuth{Type: ApproleType}},
+		{userpassMount, UserAuthEndpoint, BodyEnableAuth{Type: UserpassType}},
+	}
+	for _, authMethod := range authMethods {
+		enabled, err := hcpClient.IsAuthMethodEnabled(ctx, authMethod.mount)
+		if err != nil {
 			return err
 		}
-	}
 
-	isAuthError, err = hcpClient.CheckAuthenticationMethod(ctx, UserAuthEndpoint)
-	if err != nil {
-		if isAuthError && strings.Contains(err.Error(), StatusBadRequest) {
-			err = hcpClient.EnableAuthMethod(ctx, UserAuthEndpoint, BodyEnableAuth{
-				Type: userpassType,
-			})
-			if err != nil {
-				return err
-			}
+		if enabled {
+			continue
 		}
 
-		if !isAuthError {
+		err = hcpClient.EnableAuthMethod(ctx, authMethod.endpoint, authMethod.body)
+		if err != nil {
 			return err
 		}
 	}
 
-	isAuthError, err = hcpClient.CheckAuthenticationMethod(ctx, KvAuthEndpoint)
+	enabled, err := hcpClient.IsSecretsEngineEnabled(ctx, kvMount)
 	if err != nil {
-		if isAuthError && strings.Contains(err.Error(), StatusBadRequest) {
-			err = hcpClient.EnableAuthMethod(ctx, KvAuthEndpoint, BodySecret{
-				Type: kvType,
-			})
-			if err != nil {
-				return err
-			}
-		}
+		return err
+	}
 
-		if !isAuthError {
+	if !enabled {
+		err = hcpClient.EnableAuthMethod(ctx, KvAuthEndpoint, BodySecret{
+			Type: kvType,
+		})
+		if err != nil {
 			return err
 		}
 	}
@@ -171,66 +249,114 @@ func enableStores(ctx context.Context, hcpClient *HCPClient) error {
 	return nil
 }
 
-func (h *HCPClient) CheckAuthenticationMethod(ctx context.Context, authMethod string) (bool, error) {
-	authUrl, err := url.JoinPath(h.baseUrl, authMethod)
+// IsAuthMethodEnabled reports whether an auth method is mounted at the given path, ex. "userpass/".
+func (h *HCPClient) IsAuthMethodEnabled(ctx context.Context, mountPath string) (bool, error) {
+	authMethods, _, err := h.ListAllAuthenticationMethods(ctx)
 	if err != nil {
 		return false, err
 	}
 
-	uri, err := url.Parse(authUrl)
+	_, ok := authMethods.Data[mountPath]
+	return ok, nil
+}
+
+// IsSecretsEngineEnabled reports whether a secrets engine is mounted at the given path, ex. "kv/".
+func (h *HCPClient) IsSecretsEngineEnabled(ctx context.Context, mountPath string) (bool, error) {
+	mounts, err := h.GetMounts(ctx)
 	if err != nil {
 		return false, err
 	}
 
+	_, ok := mounts.Data[mountPath]
+	return ok, nil
+}
+
+// GetMounts. List All Secrets Engines.
+// https://developer.hashicorp.com/vault/api-docs/system/mounts#list-mounted-secrets-engines
+func (h *HCPClient) GetMounts(ctx context.Context) (*mountsAPIData, error) {
+	mountsUrl, err := url.JoinPath(h.baseUrl, MountsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(mountsUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *mountsAPIData
 	err = h.getAPIData(ctx,
 		http.MethodGet,
 		uri,
-		nil,
+		&res,
 	)
 	if err != nil {
-		return true, err
+		return nil, err
 	}
 
-	return false, nil
+	return res, nil
 }
 
-func (h *HCPClient) ListAllUsers(ctx context.Context) (*CommonAPIData, string, error) {
-	users, err := h.GetUsers(ctx)
+// ListAllUsers returns the users of the userpass mount at index pageToken of ListAuthMounts,
+// along with the mount and the token of the next mount.
+func (h *HCPClient) ListAllUsers(ctx context.Context, pageToken int) (*AuthMount, *CommonAPIData, string, error) {
+	mounts, err := h.ListAuthMounts(ctx, UserpassType)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, "", err
 	}
 
-	return users, "", nil
-}
-
-func (h *HCPClient) ListAllSecrets(ctx context.Context, token string) (*CommonAPIData, string, error) {
-	var (
-		pageToken = 0
-		err       error
-	)
-	if token != "" {
-		pageToken, err = strconv.Atoi(token)
-		if err != nil {
-			return nil, "", err
+	if pageToken >= len(mounts) {
+		if len(mounts) == 0 {
+			ctxzap.Extract(ctx).Info("userpass auth method is not enabled, skipping users")
 		}
+
+		return nil, &CommonAPIData{}, "", nil
 	}
 
-	secrets, err := h.GetSecrets(ctx, listEndpoints[pageToken])
+	mount := mounts[pageToken]
+	users, err := h.GetUsers(ctx, mount.Path)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, "", err
+	}
+
+	nextPageToken := ""
+	if pageToken+1 < len(mounts) {
+		nextPageToken = strconv.Itoa(pageToken + 1)
 	}
 
-	if pageToken == (len(listEndpoints) - 1) {
-		return secrets, "", nil
+	return &mount, users, nextPageToken, nil
+}
+
+// ListKVMounts returns the kv secrets engines, sorted by mount path.
+func (h *HCPClient) ListKVMounts(ctx context.Context) ([]SecretsMount, error) {
+	mounts, err := h.GetMounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var kvMounts []SecretsMount
+	for mountPath, mount := range mounts.Data {
+		if mount.Type != kvType {
+			continue
+		}
+
+		mount.Path = strings.TrimSuffix(mountPath, "/")
+		kvMounts = append(kvMounts, mount)
 	}
 
-	return secrets, strconv.Itoa(pageToken + 1), nil
+	slices.SortFunc(kvMounts, func(a, b SecretsMount) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return kvMounts, nil
 }
 
-// GetSecrets. List All Secrets.
-// https://developer.hashicorp.com/vault/docs/secrets/kv/kv-v1#ttls
-func (h *HCPClient) GetSecrets(ctx context.Context, secretEndpoint string) (*CommonAPIData, error) {
-	secretsUrl, err := url.JoinPath(h.baseUrl, secretEndpoint)
+// GetSecrets. List the keys of a folder of a kv mount, folder being "" for the top level.
+// Keys ending with a slash are folders.
+// https://developer.hashicorp.com/vault/api-docs/secret/kv/kv-v1#list-secrets
+// https://developer.hashicorp.com/vault/api-docs/secret/kv/kv-v2#list-secrets
+func (h *HCPClient) GetSecrets(ctx context.Context, mount SecretsMount, folder string) (*CommonAPIData, error) {
+	secretsUrl, err := url.JoinPath(h.baseUrl, "v1", mount.Path, mount.kvPrefix(), folder)
 	if err != nil {
 		return nil, err
 	}
@@ -240,7 +366,7 @@ func (h *HCPClient) GetSecrets(ctx context.Context, secretEndpoint string) (*Com
 		return nil, err
 	}
 
-	var res *CommonAPIData
+	res := &CommonAPIData{}
 	err = h.getAPIData(ctx,
 		MethodList,
 		uri,
@@ -253,10 +379,10 @@ func (h *HCPClient) GetSecrets(ctx context.Context, secretEndpoint string) (*Com
 	return res, nil
 }
 
-// GetUsers. List All Users.
+// GetUsers. List All Users of a userpass mount, ex. "userpass-contractors".
 // https://developer.hashicorp.com/vault/api-docs/auth/userpass#list-users
-func (h *HCPClient) GetUsers(ctx context.Context) (*CommonAPIData, error) {
-	usersUrl, err := url.JoinPath(h.baseUrl, UsersEndpoint)
+func (h *HCPClient) GetUsers(ctx context.Context, mount string) (*CommonAPIData, error) {
+	usersUrl, err := url.JoinPath(h.baseUrl, usersEndpoint(mount))
 	if err != nil {
 		return nil, err
 	}
@@ -266,7 +392,7 @@ func (h *HCPClient) GetUsers(ctx context.Context) (*CommonAPIData, error) {
 		return nil, err
 	}
 
-	var res *CommonAPIData
+	res := &CommonAPIData{}
 	err = h.getAPIData(ctx,
 		MethodList,
 		uri,
@@ -279,19 +405,40 @@ func (h *HCPClient) GetUsers(ctx context.Context) (*CommonAPIData, error) {
 	return res, nil
 }
 
-func (h *HCPClient) ListAllRoles(ctx context.Context) (*CommonAPIData, string, error) {
-	roles, err := h.GetRoles(ctx)
+// ListAllRoles returns the roles of the approle mount at index pageToken of ListAuthMounts,
+// along with the mount and the token of the next mount.
+func (h *HCPClient) ListAllRoles(ctx context.Context, pageToken int) (*AuthMount, *CommonAPIData, string, error) {
+	mounts, err := h.ListAuthMounts(ctx, ApproleType)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, "", err
 	}
 
-	return roles, "", nil
+	if pageToken >= len(mounts) {
+		if len(mounts) == 0 {
+			ctxzap.Extract(ctx).Info("approle auth method is not enabled, skipping roles")
+		}
+
+		return nil, &CommonAPIData{}, "", nil
+	}
+
+	mount := mounts[pageToken]
+	roles, err := h.GetRoles(ctx, mount.Path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	nextPageToken := ""
+	if pageToken+1 < len(mounts) {
+		nextPageToken = strconv.Itoa(pageToken + 1)
+	}
+
+	return &mount, roles, nextPageToken, nil
 }
 
-// GetUsers. List All Users.
+// GetRoles. List All Roles of an approle mount.
 // https://developer.hashicorp.com/vault/api-docs/auth/approle#list-roles
-func (h *HCPClient) GetRoles(ctx context.Context) (*CommonAPIData, error) {
-	rolesUrl, err := url.JoinPath(h.baseUrl, RolesEndpoint)
+func (h *HCPClient) GetRoles(ctx context.Context, mount string) (*CommonAPIData, error) {
+	rolesUrl, err := url.JoinPath(h.baseUrl, rolesEndpoint(mount))
 	if err != nil {
 		return nil, err
 	}
@@ -301,7 +448,7 @@ func (h *HCPClient) GetRoles(ctx context.Context) (*CommonAPIData, error) {
 		return nil, err
 	}
 
-	var res *CommonAPIData
+	res := &CommonAPIData{}
 	err = h.getAPIData(ctx,
 		MethodList,
 		uri,
@@ -314,6 +461,58 @@ func (h *HCPClient) GetRoles(ctx context.Context) (*CommonAPIData, error) {
 	return res, nil
 }
 
+// GetRole. Read the configuration of an AppRole.
+// https://developer.hashicorp.com/vault/api-docs/auth/approle#read-approle
+func (h *HCPClient) GetRole(ctx context.Context, mount, name string) (*RoleAPIData, error) {
+	roleUrl, err := url.JoinPath(h.baseUrl, rolesEndpoint(mount), name)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(roleUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *RoleAPIData
+	err = h.getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetRoleID. Read the RoleID of an AppRole.
+// https://developer.hashicorp.com/vault/api-docs/auth/approle#read-approle-role-id
+func (h *HCPClient) GetRoleID(ctx context.Context, mount, name string) (string, error) {
+	roleIDUrl, err := url.JoinPath(h.baseUrl, rolesEndpoint(mount), name, "role-id")
+	if err != nil {
+		return "", err
+	}
+
+	uri, err := url.Parse(roleIDUrl)
+	if err != nil {
+		return "", err
+	}
+
+	var res *RoleIDAPIData
+	err = h.getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Data.RoleID, nil
+}
+
 func (h *HCPClient) ListAllPolicies(ctx context.Context) (*PolicyAPIData, string, error) {
 	policies, err := h.GetPolicies(ctx)
 	if err != nil {
@@ -361,72 +560,179 @@ func (h *HCPClient) getAPIData(ctx context.Context,
 	return nil
 }
 
-func getError(resp *http.Response) (CustomErr, error) {
+// getError builds a VaultError out of an error response.
+func getError(method string, resp *http.Response) *VaultError {
+	vErr := &VaultError{
+		StatusCode: resp.StatusCode,
+		Method:     method,
+		Path:       resp.Request.URL.Path,
+	}
+
 	bytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return CustomErr{}, err
+		return vErr
 	}
 
 	var cErr CustomErr
-	err = json.Unmarshal(bytes, &cErr)
-	if err != nil {
-		return cErr, err
+	if err = json.Unmarshal(bytes, &cErr); err == nil {
+		vErr.Errors = cErr.Errors
+		vErr.Warnings = cErr.Warnings
 	}
 
-	return cErr, nil
+	return vErr
 }
 
 func (h *HCPClient) doRequest(ctx context.Context, method, endpointUrl string, res interface{}, body interface{}) error {
-	var (
-		resp *http.Response
-		err  error
-	)
 	urlAddress, err := url.Parse(endpointUrl)
 	if err != nil {
 		return err
 	}
 
+	if h.readOnly && method != MethodList && method != http.MethodGet && !isReadOnlyPost(method, urlAddress) {
+		return ErrReadOnly
+	}
+
+	var token string
+	send := func() (*http.Response, error) {
+		var err error
+		token, err = h.getToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return h.send(ctx, method, urlAddress, token, res, body)
+	}
+
+	resp, err := h.sendWithRetry(ctx, method, send)
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		retry, recoverErr := h.recoverToken(ctx, token)
+		if recoverErr != nil {
+			return recoverErr
+		}
+
+		if retry {
+			// The token was revoked or expired before its lease ended and was replaced, retry once.
+			ctxzap.Extract(ctx).Debug("vault rejected the token, retrying with a new one", zap.String("url", endpointUrl))
+			resp, err = h.sendWithRetry(ctx, method, send)
+		}
+	}
+
+	if err != nil && resp != nil && resp.StatusCode >= http.StatusBadRequest {
+		vErr := getError(method, resp)
+		// Vault answers a LIST on a path without any key with a 404 carrying no error.
+		if method == MethodList && vErr.StatusCode == http.StatusNotFound && len(vErr.Errors) == 0 {
+			return nil
+		}
+
+		return vErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *HCPClient) send(ctx context.Context, method string, urlAddress *url.URL, token string, res interface{}, body interface{}) (*http.Response, error) {
+	var resp *http.Response
+	options := []uhttp.RequestOption{
+		uhttp.WithHeader(AuthHeaderName, token),
+		uhttp.WithJSONBody(body),
+	}
+
+	uri, ok := namespacedURL(urlAddress, h.namespace)
+	if !ok {
+		options = append(options, uhttp.WithHeader(NamespaceHeaderName, h.namespace))
+	}
+
 	req, err := h.httpClient.NewRequest(ctx,
 		method,
-		urlAddress,
-		uhttp.WithHeader(AuthHeaderName, h.getToken()),
-		uhttp.WithJSONBody(body),
+		uri,
+		options...,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	switch method {
-	case MethodList, http.MethodGet:
+	case http.MethodGet:
+		if h.uncached {
+			return h.sendUncached(req, res)
+		}
+
+		fallthrough
+	case MethodList:
 		resp, err = h.httpClient.Do(req, uhttp.WithResponse(&res))
 		if resp != nil {
 			defer resp.Body.Close()
 		}
 	case http.MethodPost:
-		resp, err = h.httpClient.Do(req)
+		resp, err = h.httpClient.Do(req, withOptionalJSONResponse(res))
 		if resp != nil {
 			defer resp.Body.Close()
 		}
 	}
 
-	if resp != nil && (resp.StatusCode == http.StatusNotFound ||
-		resp.StatusCode == http.StatusBadRequest) {
-		cErr, err := getError(resp)
-		if err != nil {
-			return err
+	return resp, err
+}
+
+// sendUncached sends a request through the underlying http client, which unlike uhttp does not cache GET responses.
+func (h *HCPClient) sendUncached(req *http.Request, res interface{}) (*http.Response, error) {
+	resp, err := h.httpClient.HttpClient.Do(req)
+	if err != nil {
+		return nil, wrapTransportError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	// Keep the body readable for getError once the response is closed.
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp, fmt.Errorf("hcp-client: unexpected status code: %d", resp.StatusCode)
+	}
+
+	if len(body) == 0 {
+		return resp, nil
+	}
+
+	return resp, json.Unmarshal(body, res)
+}
+
+// wrapTransportError turns the timeouts and temporary failures of a request into the gRPC statuses uhttp gives
+// them, so the requests sent around the response cache are retried like the others.
+func wrapTransportError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return uhttp.WrapErrors(codes.DeadlineExceeded, fmt.Sprintf("request timeout: %v", urlErr.URL), urlErr)
 		}
 
-		// There is no data ot It's already authorized
-		if len(cErr.Errors) == 0 || strings.Contains(cErr.Errors[0], "path is already in use") {
-			return nil
+		if urlErr.Temporary() {
+			return uhttp.WrapErrors(codes.Unavailable, fmt.Sprintf("temporary error: %v", urlErr.URL), urlErr)
 		}
 	}
 
-	if err != nil {
-		return err
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, "request timeout")
 	}
 
-	return nil
+	return err
+}
+
+// withOptionalJSONResponse decodes the response body when there is one, write requests usually answer 204 No Content.
+func withOptionalJSONResponse(res any) uhttp.DoOption {
+	return func(resp *uhttp.WrapperResponse) error {
+		if len(resp.Body) == 0 {
+			return nil
+		}
+
+		return uhttp.WithJSONResponse(res)(resp)
+	}
 }
 
 // EnableAuthMethod. Enables you to use an auth method.
@@ -447,7 +753,7 @@ func (h *HCPClient) EnableAuthMethod(ctx context.Context, apiUrl string, body an
 }
 
 func (h *HCPClient) AddUsers(ctx context.Context, name, pwd string) error {
-	endpointUrl, err := url.JoinPath(h.baseUrl, UsersEndpoint, name)
+	endpointUrl, err := url.JoinPath(h.baseUrl, usersEndpoint(DefaultUserpassMount), name)
 	if err != nil {
 		return err
 	}
@@ -465,7 +771,7 @@ func (h *HCPClient) AddUsers(ctx context.Context, name, pwd string) error {
 }
 
 func (h *HCPClient) AddRoles(ctx context.Context, name string) error {
-	endpointUrl, err := url.JoinPath(h.baseUrl, RolesEndpoint, name)
+	endpointUrl, err := url.JoinPath(h.baseUrl, rolesEndpoint(DefaultAppRoleMount), name)
 	if err != nil {
 		return err
 	}
@@ -485,6 +791,36 @@ func (h *HCPClient) AddRoles(ctx context.Context, name string) error {
 	return nil
 }
 
+// GetSecretMetadata. Read the metadata and the version history of a kv v2 secret, never its data.
+// https://developer.hashicorp.com/vault/api-docs/secret/kv/kv-v2#read-secret-metadata
+func (h *HCPClient) GetSecretMetadata(ctx context.Context, mount SecretsMount, secretPath string) (*SecretMetadataAPIData, error) {
+	if mount.KVVersion() != 2 {
+		return nil, fmt.Errorf("hcp-client: %s is not a kv v2 mount", mount.Path)
+	}
+
+	metadataUrl, err := url.JoinPath(h.baseUrl, "v1", mount.Path, "metadata", secretPath)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(metadataUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *SecretMetadataAPIData
+	err = h.getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 func (h *HCPClient) AddSecrets(ctx context.Context, name, value string) error {
 	endpointUrl, err := url.JoinPath(h.baseUrl, KvEndpoint, name)
 	if err != nil {
@@ -501,8 +837,8 @@ func (h *HCPClient) AddSecrets(ctx context.Context, name, value string) error {
 	return nil
 }
 
-func (h *HCPClient) GetUser(ctx context.Context, name string) (*UserAPIData, error) {
-	userUrl, err := url.JoinPath(h.baseUrl, UsersEndpoint, name)
+func (h *HCPClient) GetUser(ctx context.Context, mount, name string) (*UserAPIData, error) {
+	userUrl, err := url.JoinPath(h.baseUrl, usersEndpoint(mount), name)
 	if err != nil {
 		return nil, err
 	}
@@ -549,6 +885,38 @@ func (h *HCPClient) ListAllAuthenticationMethods(ctx context.Context) (*authMeth
 	return res, "", nil
 }
 
+// ListAuthMounts returns the auth methods of the given type, ex. "userpass", sorted by mount path.
+func (h *HCPClient) ListAuthMounts(ctx context.Context, mountType string) ([]AuthMount, error) {
+	authMethods, _, err := h.ListAllAuthenticationMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mounts []AuthMount
+	for mountPath, mount := range authMethods.Data {
+		if mount.Type != mountType {
+			continue
+		}
+
+		mount.Path = strings.TrimSuffix(mountPath, "/")
+		mounts = append(mounts, mount)
+	}
+
+	slices.SortFunc(mounts, func(a, b AuthMount) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return mounts, nil
+}
+
+func usersEndpoint(mount string) string {
+	return fmt.Sprintf("v1/auth/%s/users", mount)
+}
+
+func rolesEndpoint(mount string) string {
+	return fmt.Sprintf("v1/auth/%s/role", mount)
+}
+
 func (h *HCPClient) ListAllGroups(ctx context.Context) (*groupsAPIData, string, error) {
 	groupUrl, err := url.JoinPath(h.baseUrl, GroupsEndpoint)
 	if err != nil {
@@ -560,7 +928,7 @@ func (h *HCPClient) ListAllGroups(ctx context.Context) (*groupsAPIData, string,
 		return nil, "", err
 	}
 
-	var res *groupsAPIData
+	res := &groupsAPIData{}
 	err = h.getAPIData(ctx,
 		MethodList,
 		uri,
@@ -584,7 +952,7 @@ func (h *HCPClient) ListAllEntities(ctx context.Context) (*entityAPIData, string
 		return nil, "", err
 	}
 
-	var res *entityAPIData
+	res := &entityAPIData{}
 	err = h.getAPIData(ctx,
 		MethodList,
 		uri,
@@ -597,20 +965,171 @@ func (h *HCPClient) ListAllEntities(ctx context.Context) (*entityAPIData, string
 	return res, "", nil
 }
 
+// GetGroup. Read an identity group by its ID.
+// https://developer.hashicorp.com/vault/api-docs/secret/identity/group#read-group-by-id
+func (h *HCPClient) GetGroup(ctx context.Context, id string) (*GroupAPIData, error) {
+	groupUrl, err := url.JoinPath(h.baseUrl, GroupsEndpoint, id)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(groupUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *GroupAPIData
+	err = h.getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetEntity. Read an identity entity by its ID.
+// https://developer.hashicorp.com/vault/api-docs/secret/identity/entity#read-entity-by-id
+func (h *HCPClient) GetEntity(ctx context.Context, id string) (*EntityAPIData, error) {
+	entityUrl, err := url.JoinPath(h.baseUrl, EntityEndpoint, id)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(entityUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *EntityAPIData
+	err = h.getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 // UpdateUserPolicy. Update policies for an existing user.
 // https://developer.hashicorp.com/vault/api-docs/auth/userpass#update-policies-on-user
-func (h *HCPClient) UpdateUserPolicy(ctx context.Context, policy []string, name string) error {
-	endpointUrl, err := url.JoinPath(h.baseUrl, UsersEndpoint, name)
+func (h *HCPClient) UpdateUserPolicy(ctx context.Context, policy []string, mount, name string) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, usersEndpoint(mount), name)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyUpdateTokenPolicies{
+		TokenPolicies: nonNil(policy),
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRolePolicies. Replace the token policies of an existing AppRole, its other settings are kept.
+// https://developer.hashicorp.com/vault/api-docs/auth/approle#create-update-approle
+func (h *HCPClient) UpdateRolePolicies(ctx context.Context, policies []string, mount, name string) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, rolesEndpoint(mount), name)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyUpdateTokenPolicies{
+		TokenPolicies: nonNil(policies),
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateGroupPolicies. Replace the policies of an identity group, its other fields are kept.
+// https://developer.hashicorp.com/vault/api-docs/secret/identity/group#update-group-by-id
+func (h *HCPClient) UpdateGroupPolicies(ctx context.Context, policies []string, id string) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, GroupsEndpoint, id)
 	if err != nil {
 		return err
 	}
 
 	var res any
-	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyUpdateUserPolicy{
-		TokenPolicies: policy,
+	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyUpdatePolicies{
+		Policies: nonNil(policies),
 	}); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// UpdateGroupMemberEntities. Replace the member entities of an internal identity group, its other fields are kept.
+// https://developer.hashicorp.com/vault/api-docs/secret/identity/group#update-group-by-id
+func (h *HCPClient) UpdateGroupMemberEntities(ctx context.Context, entityIDs []string, id string) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, GroupsEndpoint, id)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyGroupMemberEntities{
+		MemberEntityIDs: nonNil(entityIDs),
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateGroupMemberGroups. Replace the member groups of an internal identity group, its other fields are kept.
+// https://developer.hashicorp.com/vault/api-docs/secret/identity/group#update-group-by-id
+func (h *HCPClient) UpdateGroupMemberGroups(ctx context.Context, groupIDs []string, id string) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, GroupsEndpoint, id)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyGroupMemberGroups{
+		MemberGroupIDs: nonNil(groupIDs),
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateEntityPolicies. Replace the policies of an identity entity, its other fields are kept.
+// https://developer.hashicorp.com/vault/api-docs/secret/identity/entity#update-entity-by-id
+func (h *HCPClient) UpdateEntityPolicies(ctx context.Context, policies []string, id string) error {
+	endpointUrl, err := url.JoinPath(h.baseUrl, EntityEndpoint, id)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err = h.doRequest(ctx, http.MethodPost, endpointUrl, &res, bodyUpdatePolicies{
+		Policies: nonNil(policies),
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// nonNil makes an empty list marshal to [] since Vault ignores a null field instead of clearing it.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
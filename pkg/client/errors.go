@@ -0,0 +1,80 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VaultError is an error response returned by the Vault API.
+// https://developer.hashicorp.com/vault/api-docs#error-response
+type VaultError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Errors     []string
+	Warnings   []string
+}
+
+func (e *VaultError) Error() string {
+	msg := fmt.Sprintf("hcp-client: %s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if len(e.Errors) > 0 {
+		msg += ": " + strings.Join(e.Errors, "; ")
+	}
+
+	if len(e.Warnings) > 0 {
+		msg += " (warnings: " + strings.Join(e.Warnings, "; ") + ")"
+	}
+
+	return msg
+}
+
+// Code maps the HTTP status of the error to the gRPC code the baton runtime uses to decide whether to retry.
+func (e *VaultError) Code() codes.Code {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return codes.InvalidArgument
+	case e.StatusCode == http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case e.StatusCode == http.StatusForbidden:
+		return codes.PermissionDenied
+	case e.StatusCode == http.StatusNotFound:
+		return codes.NotFound
+	case e.StatusCode == http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case e.StatusCode == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case e.StatusCode == http.StatusNotImplemented:
+		return codes.Unimplemented
+	case e.StatusCode == http.StatusInternalServerError:
+		return codes.Internal
+	case e.StatusCode >= http.StatusBadGateway:
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
+
+// GRPCStatus makes status.FromError and status.Code understand VaultError.
+func (e *VaultError) GRPCStatus() *status.Status {
+	return status.New(e.Code(), e.Error())
+}
+
+// IsNotFound reports whether err is a Vault 404, ex. a missing user or a path nothing is mounted on.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsPermissionDenied reports whether err is a Vault 403.
+func IsPermissionDenied(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	var vErr *VaultError
+	return errors.As(err, &vErr) && vErr.StatusCode == statusCode
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVaultErrorCode(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		code       codes.Code
+	}{
+		{http.StatusBadRequest, codes.InvalidArgument},
+		{http.StatusForbidden, codes.PermissionDenied},
+		{http.StatusNotFound, codes.NotFound},
+		{http.StatusTooManyRequests, codes.ResourceExhausted},
+		{http.StatusInternalServerError, codes.Internal},
+		{http.StatusBadGateway, codes.Unavailable},
+		{http.StatusServiceUnavailable, codes.Unavailable},
+	}
+
+	for _, testCase := range testCases {
+		err := fmt.Errorf("wrapped: %w", &VaultError{
+			StatusCode: testCase.statusCode,
+			Method:     MethodList,
+			Path:       "/v1/auth/userpass/users",
+			Errors:     []string{"boom"},
+		})
+		require.Equal(t, testCase.code, status.Code(err), http.StatusText(testCase.statusCode))
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &VaultError{StatusCode: http.StatusNotFound})
+	require.True(t, IsNotFound(err))
+	require.False(t, IsPermissionDenied(err))
+	require.False(t, IsNotFound(fmt.Errorf("not a vault error")))
+}
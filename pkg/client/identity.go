@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const LookupEntityEndpoint = "v1/identity/lookup/entity"
+
+// LookupEntityByAlias returns the entity owning the alias of an auth method, nil if there is none.
+// https://developer.hashicorp.com/vault/api-docs/secret/identity/lookup#lookup-an-entity
+func (h *HCPClient) LookupEntityByAlias(ctx context.Context, aliasName, mountAccessor string) (*EntityAPIData, error) {
+	lookupUrl, err := url.JoinPath(h.baseUrl, LookupEntityEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Vault answers 204 No Content when no entity has the alias, leaving res nil.
+	var res *EntityAPIData
+	if err = h.doRequest(ctx, http.MethodPost, lookupUrl, &res, bodyLookupEntity{
+		AliasName:          aliasName,
+		AliasMountAccessor: mountAccessor,
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
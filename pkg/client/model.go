@@ -1,7 +1,59 @@
 package client
 
+import (
+	"sync"
+	"time"
+)
+
 type auth struct {
+	mu          sync.Mutex
 	bearerToken string
+	// login is set for auth methods that obtain the token from Vault, nil for static tokens.
+	login     loginRequest
+	expiresAt time.Time
+	renewable bool
+}
+
+type TokenLookupAPIData struct {
+	RequestID string          `json:"request_id,omitempty"`
+	Data      TokenLookupData `json:"data,omitempty"`
+}
+
+type TokenLookupData struct {
+	Accessor    string   `json:"accessor,omitempty"`
+	DisplayName string   `json:"display_name,omitempty"`
+	EntityID    string   `json:"entity_id,omitempty"`
+	ExpireTime  string   `json:"expire_time,omitempty"`
+	Policies    []string `json:"policies,omitempty"`
+	Renewable   bool     `json:"renewable,omitempty"`
+	TTL         int      `json:"ttl,omitempty"`
+}
+
+type loginAPIData struct {
+	RequestID string    `json:"request_id,omitempty"`
+	Auth      loginAuth `json:"auth,omitempty"`
+}
+
+type loginAuth struct {
+	ClientToken   string   `json:"client_token,omitempty"`
+	Accessor      string   `json:"accessor,omitempty"`
+	Policies      []string `json:"policies,omitempty"`
+	LeaseDuration int      `json:"lease_duration,omitempty"`
+	Renewable     bool     `json:"renewable,omitempty"`
+}
+
+type bodyRoleJWTLogin struct {
+	Role string `json:"role"`
+	JWT  string `json:"jwt"`
+}
+
+type bodyCertLogin struct {
+	Name string `json:"name,omitempty"`
+}
+
+type bodyAppRoleLogin struct {
+	RoleID   string `json:"role_id"`
+	SecretID string `json:"secret_id,omitempty"`
 }
 
 type CommonAPIData struct {
@@ -15,9 +67,11 @@ type Data struct {
 }
 
 type APIResource struct {
-	ID        string `json:"id,omitempty"`
-	Name      string `json:"name,omitempty"`
-	MountType string `json:"mount_type,omitempty"`
+	ID            string `json:"id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	MountType     string `json:"mount_type,omitempty"`
+	MountPath     string `json:"mount_path,omitempty"`
+	MountAccessor string `json:"mount_accessor,omitempty"`
 }
 
 type PolicyAPIData struct {
@@ -51,6 +105,37 @@ type UserData struct {
 	TokenType            string   `json:"token_type,omitempty"`
 }
 
+type RoleAPIData struct {
+	RequestID string   `json:"request_id,omitempty"`
+	Data      RoleData `json:"data,omitempty"`
+}
+
+type RoleData struct {
+	BindSecretID         bool     `json:"bind_secret_id,omitempty"`
+	LocalSecretIDs       bool     `json:"local_secret_ids,omitempty"`
+	SecretIDBoundCidrs   []string `json:"secret_id_bound_cidrs,omitempty"`
+	SecretIDNumUses      int      `json:"secret_id_num_uses,omitempty"`
+	SecretIDTTL          int      `json:"secret_id_ttl,omitempty"`
+	TokenBoundCidrs      []string `json:"token_bound_cidrs,omitempty"`
+	TokenExplicitMaxTTL  int      `json:"token_explicit_max_ttl,omitempty"`
+	TokenMaxTTL          int      `json:"token_max_ttl,omitempty"`
+	TokenNoDefaultPolicy bool     `json:"token_no_default_policy,omitempty"`
+	TokenNumUses         int      `json:"token_num_uses,omitempty"`
+	TokenPeriod          int      `json:"token_period,omitempty"`
+	TokenPolicies        []string `json:"token_policies,omitempty"`
+	TokenTTL             int      `json:"token_ttl,omitempty"`
+	TokenType            string   `json:"token_type,omitempty"`
+}
+
+type RoleIDAPIData struct {
+	RequestID string     `json:"request_id,omitempty"`
+	Data      RoleIDData `json:"data,omitempty"`
+}
+
+type RoleIDData struct {
+	RoleID string `json:"role_id,omitempty"`
+}
+
 type bodyUsers struct {
 	Password        string   `json:"password"`
 	TokenPolicies   []string `json:"token_policies"`
@@ -70,10 +155,14 @@ type BodyEnableAuth struct {
 	Type string `json:"type"`
 }
 
-type bodyUpdateUserPolicy struct {
+type bodyUpdateTokenPolicies struct {
 	TokenPolicies []string `json:"token_policies"`
 }
 
+type bodyUpdatePolicies struct {
+	Policies []string `json:"policies"`
+}
+
 type BodySecret struct {
 	Type                  string  `json:"type"`
 	Description           string  `json:"description"`
@@ -100,9 +189,53 @@ type bodySecrets struct {
 }
 
 type authMethodsAPIData struct {
-	RequestID string                 `json:"request_id,omitempty"`
-	Data      map[string]interface{} `json:"data,omitempty"`
-	MountType string                 `json:"mount_type,omitempty"`
+	RequestID string               `json:"request_id,omitempty"`
+	Data      map[string]AuthMount `json:"data,omitempty"`
+	MountType string               `json:"mount_type,omitempty"`
+}
+
+// AuthMount is an auth method enabled in a namespace.
+type AuthMount struct {
+	// Path is the mount path without its trailing slash, ex. "userpass-contractors".
+	Path        string `json:"-"`
+	Type        string `json:"type,omitempty"`
+	Accessor    string `json:"accessor,omitempty"`
+	Description string `json:"description,omitempty"`
+	Local       bool   `json:"local,omitempty"`
+}
+
+type mountsAPIData struct {
+	RequestID string                  `json:"request_id,omitempty"`
+	Data      map[string]SecretsMount `json:"data,omitempty"`
+	MountType string                  `json:"mount_type,omitempty"`
+}
+
+// SecretsMount is a secrets engine enabled in a namespace.
+type SecretsMount struct {
+	// Path is the mount path without its trailing slash, ex. "kv".
+	Path        string            `json:"-"`
+	Type        string            `json:"type,omitempty"`
+	Accessor    string            `json:"accessor,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Options     map[string]string `json:"options,omitempty"`
+}
+
+// KVVersion returns 2 for versioned kv mounts and 1 otherwise.
+func (m SecretsMount) KVVersion() int {
+	if m.Options["version"] == "2" {
+		return 2
+	}
+
+	return 1
+}
+
+// kvPrefix is the path segment listing and metadata requests go through, empty for kv v1.
+func (m SecretsMount) kvPrefix() string {
+	if m.KVVersion() == 2 {
+		return "metadata"
+	}
+
+	return ""
 }
 
 type groupsAPIData struct {
@@ -128,3 +261,130 @@ type genericData struct {
 	KeyInfo map[string]group `json:"key_info,omitempty"`
 	Keys    []string         `json:"keys,omitempty"`
 }
+
+type HealthAPIData struct {
+	Initialized        bool   `json:"initialized"`
+	Sealed             bool   `json:"sealed"`
+	Standby            bool   `json:"standby"`
+	PerformanceStandby bool   `json:"performance_standby"`
+	Version            string `json:"version,omitempty"`
+	ClusterName        string `json:"cluster_name,omitempty"`
+}
+
+type SealStatusAPIData struct {
+	Type        string `json:"type,omitempty"`
+	Initialized bool   `json:"initialized"`
+	Sealed      bool   `json:"sealed"`
+	T           int    `json:"t,omitempty"`
+	N           int    `json:"n,omitempty"`
+	Progress    int    `json:"progress,omitempty"`
+	Version     string `json:"version,omitempty"`
+}
+
+type bodyCapabilities struct {
+	Paths []string `json:"paths"`
+}
+
+type capabilitiesAPIData struct {
+	RequestID string              `json:"request_id,omitempty"`
+	Data      map[string][]string `json:"data,omitempty"`
+}
+
+type SecretMetadataAPIData struct {
+	RequestID string         `json:"request_id,omitempty"`
+	Data      SecretMetadata `json:"data,omitempty"`
+}
+
+type SecretMetadata struct {
+	CreatedTime        string                   `json:"created_time,omitempty"`
+	UpdatedTime        string                   `json:"updated_time,omitempty"`
+	CurrentVersion     int                      `json:"current_version,omitempty"`
+	OldestVersion      int                      `json:"oldest_version,omitempty"`
+	MaxVersions        int                      `json:"max_versions,omitempty"`
+	CasRequired        bool                     `json:"cas_required,omitempty"`
+	DeleteVersionAfter string                   `json:"delete_version_after,omitempty"`
+	CustomMetadata     map[string]string        `json:"custom_metadata,omitempty"`
+	Versions           map[string]SecretVersion `json:"versions,omitempty"`
+}
+
+type SecretVersion struct {
+	CreatedTime  string `json:"created_time,omitempty"`
+	DeletionTime string `json:"deletion_time,omitempty"`
+	Destroyed    bool   `json:"destroyed,omitempty"`
+}
+
+type ACLPolicyAPIData struct {
+	RequestID string        `json:"request_id,omitempty"`
+	Data      ACLPolicyData `json:"data,omitempty"`
+}
+
+type ACLPolicyData struct {
+	Name   string `json:"name,omitempty"`
+	Policy string `json:"policy,omitempty"`
+}
+
+type bodyACLPolicy struct {
+	Policy string `json:"policy"`
+}
+
+type bodyGroupMemberEntities struct {
+	MemberEntityIDs []string `json:"member_entity_ids"`
+}
+
+type bodyGroupMemberGroups struct {
+	MemberGroupIDs []string `json:"member_group_ids"`
+}
+
+type GroupAPIData struct {
+	RequestID string    `json:"request_id,omitempty"`
+	Data      GroupData `json:"data,omitempty"`
+}
+
+const (
+	GroupTypeInternal = "internal"
+	GroupTypeExternal = "external"
+)
+
+type GroupData struct {
+	ID              string            `json:"id,omitempty"`
+	Name            string            `json:"name,omitempty"`
+	Type            string            `json:"type,omitempty"`
+	Policies        []string          `json:"policies,omitempty"`
+	MemberEntityIDs []string          `json:"member_entity_ids,omitempty"`
+	MemberGroupIDs  []string          `json:"member_group_ids,omitempty"`
+	ParentGroupIDs  []string          `json:"parent_group_ids,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	Alias           AliasData         `json:"alias,omitempty"`
+}
+
+type EntityAPIData struct {
+	RequestID string     `json:"request_id,omitempty"`
+	Data      EntityData `json:"data,omitempty"`
+}
+
+type EntityData struct {
+	ID                string            `json:"id,omitempty"`
+	Name              string            `json:"name,omitempty"`
+	Disabled          bool              `json:"disabled,omitempty"`
+	Policies          []string          `json:"policies,omitempty"`
+	DirectGroupIDs    []string          `json:"direct_group_ids,omitempty"`
+	GroupIDs          []string          `json:"group_ids,omitempty"`
+	InheritedGroupIDs []string          `json:"inherited_group_ids,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	Aliases           []AliasData       `json:"aliases,omitempty"`
+}
+
+type bodyLookupEntity struct {
+	AliasName          string `json:"alias_name"`
+	AliasMountAccessor string `json:"alias_mount_accessor"`
+}
+
+// AliasData is an entity alias or a group alias, the name an auth method knows an identity by.
+type AliasData struct {
+	ID            string `json:"id,omitempty"`
+	CanonicalID   string `json:"canonical_id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	MountAccessor string `json:"mount_accessor,omitempty"`
+	MountPath     string `json:"mount_path,omitempty"`
+	MountType     string `json:"mount_type,omitempty"`
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	NamespaceHeaderName = "X-Vault-Namespace"
+	NamespacesEndpoint  = "v1/sys/namespaces"
+	// NamespaceIDSeparator separates the namespace from the ID in a namespaced ID. Mount and namespace paths
+	// are joined with "/", so a "/" there would not tell a namespace from the first segment of a mount path.
+	NamespaceIDSeparator = "::"
+)
+
+// WithNamespace sets the namespace every request is sent to, ex. "bu1/team-a". Empty means the root namespace.
+func (h *HCPClient) WithNamespace(namespace string) {
+	h.rootNamespace = NormalizeNamespace(namespace)
+	h.namespace = h.rootNamespace
+}
+
+// WithNamespaceDiscovery makes ListAllNamespaces descend into the child namespaces of the configured one.
+func (h *HCPClient) WithNamespaceDiscovery(discover bool) {
+	h.discoverNamespaces = discover
+}
+
+// NormalizeNamespace turns a namespace path into the "a/b/" form used by Vault, "" being the root namespace.
+func NormalizeNamespace(namespace string) string {
+	namespace = strings.Trim(namespace, "/")
+	if namespace == "" {
+		return ""
+	}
+
+	return namespace + "/"
+}
+
+// RootNamespace returns the namespace the client was configured with.
+func (h *HCPClient) RootNamespace() string {
+	return h.rootNamespace
+}
+
+// DiscoversNamespaces reports whether child namespaces of the configured one are synced.
+func (h *HCPClient) DiscoversNamespaces() bool {
+	return h.discoverNamespaces
+}
+
+// Namespace returns a client that sends its requests to the given namespace.
+// It shares the http client and the token of h.
+func (h *HCPClient) Namespace(namespace string) *HCPClient {
+	cli := *h
+	cli.namespace = NormalizeNamespace(namespace)
+	return &cli
+}
+
+// GetNamespaces. List the direct child namespaces of the client namespace.
+// https://developer.hashicorp.com/vault/api-docs/system/namespaces#list-namespaces
+func (h *HCPClient) GetNamespaces(ctx context.Context) ([]string, error) {
+	namespacesUrl, err := url.JoinPath(h.baseUrl, NamespacesEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(namespacesUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CommonAPIData{}
+	err = h.getAPIData(ctx,
+		MethodList,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(res.Data.Keys))
+	for _, key := range res.Data.Keys {
+		namespaces = append(namespaces, h.namespace+NormalizeNamespace(key))
+	}
+
+	return namespaces, nil
+}
+
+// namespaceCache keeps the namespaces found by ListAllNamespaces since every namespaced ID is split against them.
+// It is emptied by StartSync so every sync sees the namespaces created since the previous one.
+type namespaceCache struct {
+	mu         sync.Mutex
+	namespaces []string
+}
+
+func (c *namespaceCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.namespaces = nil
+}
+
+// ListAllNamespaces returns the configured namespace followed, when discovery is enabled,
+// by every namespace nested under it.
+func (h *HCPClient) ListAllNamespaces(ctx context.Context) ([]string, error) {
+	namespaces := []string{h.rootNamespace}
+	if !h.discoverNamespaces {
+		return namespaces, nil
+	}
+
+	if h.namespaceCache != nil {
+		h.namespaceCache.mu.Lock()
+		defer h.namespaceCache.mu.Unlock()
+
+		if h.namespaceCache.namespaces != nil {
+			return slices.Clone(h.namespaceCache.namespaces), nil
+		}
+	}
+
+	for i := 0; i < len(namespaces); i++ {
+		children, err := h.Namespace(namespaces[i]).GetNamespaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		namespaces = append(namespaces, children...)
+	}
+
+	if h.namespaceCache != nil {
+		h.namespaceCache.namespaces = slices.Clone(namespaces)
+	}
+
+	return namespaces, nil
+}
+
+// SplitNamespacedID splits an ID qualified by QualifyID into its namespace and the ID inside that namespace.
+// IDs without the prefix of a known namespace belong to the root namespace.
+func (h *HCPClient) SplitNamespacedID(ctx context.Context, id string) (string, string, error) {
+	prefix, rest, ok := strings.Cut(id, NamespaceIDSeparator)
+	if !ok {
+		return "", id, nil
+	}
+
+	namespaces, err := h.ListAllNamespaces(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	namespace := NormalizeNamespace(prefix)
+	if namespace == "" || !slices.Contains(namespaces, namespace) {
+		return "", id, nil
+	}
+
+	return namespace, rest, nil
+}
+
+// namespacedURL moves the namespace into the path of an API URL, ex. "v1/bu1/sys/mounts". Vault accepts it there
+// as well as in the X-Vault-Namespace header, but only the path is part of the key of the GET response cache,
+// so this keeps the responses of each namespace apart. It reports false for URLs outside the API.
+// https://developer.hashicorp.com/vault/docs/enterprise/namespaces#usage
+func namespacedURL(uri *url.URL, namespace string) (*url.URL, bool) {
+	if namespace == "" {
+		return uri, true
+	}
+
+	base, rest, ok := strings.Cut(uri.Path, "/v1/")
+	if !ok {
+		return uri, false
+	}
+
+	namespaced := *uri
+	namespaced.Path = base + "/v1/" + namespace + rest
+	namespaced.RawPath = ""
+	return &namespaced, true
+}
+
+// QualifyID prefixes an ID with its namespace path so IDs from different namespaces never collide,
+// ex. "bu1/team-a::userpass/alice". IDs of the root namespace are left as is.
+func QualifyID(namespace, id string) string {
+	namespace = strings.Trim(namespace, "/")
+	if namespace == "" {
+		return id
+	}
+
+	return namespace + NamespaceIDSeparator + id
+}
+
+// UnqualifyID returns the ID inside its namespace of an ID qualified by QualifyID.
+func UnqualifyID(namespace, id string) string {
+	namespace = strings.Trim(namespace, "/")
+	if namespace == "" {
+		return id
+	}
+
+	return strings.TrimPrefix(id, namespace+NamespaceIDSeparator)
+}
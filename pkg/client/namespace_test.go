@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespacedURL(t *testing.T) {
+	testCases := []struct {
+		url       string
+		namespace string
+		expected  string
+		ok        bool
+	}{
+		{"https://vault:8200/v1/sys/mounts", "", "https://vault:8200/v1/sys/mounts", true},
+		{"https://vault:8200/v1/sys/mounts", "bu1/team-a/", "https://vault:8200/v1/bu1/team-a/sys/mounts", true},
+		{"https://proxy/vault/v1/auth/userpass/users?list=true", "bu1/", "https://proxy/vault/v1/bu1/auth/userpass/users?list=true", true},
+		{"https://vault:8200/sys/mounts", "bu1/", "https://vault:8200/sys/mounts", false},
+	}
+	for _, tc := range testCases {
+		uri, err := url.Parse(tc.url)
+		require.Nil(t, err)
+
+		namespaced, ok := namespacedURL(uri, tc.namespace)
+		require.Equal(t, tc.ok, ok, tc.url)
+		require.Equal(t, tc.expected, namespaced.String(), tc.url)
+	}
+}
+
+func TestSplitNamespacedID(t *testing.T) {
+	cli := &HCPClient{
+		discoverNamespaces: true,
+		namespaceCache:     &namespaceCache{namespaces: []string{"", "finance/", "finance/team-a/"}},
+	}
+
+	testCases := []struct {
+		namespace string
+		id        string
+	}{
+		{"", "finance/secret-a"},
+		{"", "userpass/alice"},
+		{"finance/", "finance/secret-a"},
+		{"finance/team-a/", "userpass/alice"},
+		{"finance/team-a/", "a::b"},
+	}
+	for _, tc := range testCases {
+		qualified := QualifyID(tc.namespace, tc.id)
+		require.Equal(t, tc.id, UnqualifyID(tc.namespace, qualified), qualified)
+
+		namespace, id, err := cli.SplitNamespacedID(context.Background(), qualified)
+		require.Nil(t, err)
+		require.Equal(t, tc.namespace, namespace, qualified)
+		require.Equal(t, tc.id, id, qualified)
+	}
+
+	// An ID of the root namespace holding the separator is not mistaken for a namespaced one.
+	namespace, id, err := cli.SplitNamespacedID(context.Background(), "hr::payroll")
+	require.Nil(t, err)
+	require.Equal(t, "", namespace)
+	require.Equal(t, "hr::payroll", id)
+}
+
+func TestStartSyncResetsNamespaces(t *testing.T) {
+	cli := &HCPClient{
+		namespaceCache: &namespaceCache{namespaces: []string{"", "finance/"}},
+		syncs:          &atomic.Uint64{},
+	}
+
+	cli.StartSync()
+	require.Nil(t, cli.namespaceCache.namespaces)
+	require.EqualValues(t, 1, cli.SyncGeneration())
+}
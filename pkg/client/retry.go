@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	DefaultMaxRetries = 5
+	retryBaseDelay    = 500 * time.Millisecond
+	retryMaxDelay     = 30 * time.Second
+)
+
+// rateLimitState remembers that Vault throttled a request until the connector reports it to the runtime.
+type rateLimitState struct {
+	mu   sync.Mutex
+	last *v2.RateLimitDescription
+}
+
+// WithMaxRetries sets how many times a GET or LIST request is retried while Vault is sealed,
+// on a standby node, over a rate limit quota or electing a leader. Zero disables retries.
+func (h *HCPClient) WithMaxRetries(maxRetries int) {
+	h.maxRetries = maxRetries
+}
+
+// WithOwnRateLimit returns a copy of the client that keeps the throttling of its own requests apart,
+// so each resource syncer only reports the rate limits its requests hit.
+func (h *HCPClient) WithOwnRateLimit() *HCPClient {
+	cli := *h
+	cli.rateLimit = &rateLimitState{}
+	return &cli
+}
+
+// TakeRateLimit returns how Vault throttled the requests made since the previous call, nil if it did not.
+func (h *HCPClient) TakeRateLimit() *v2.RateLimitDescription {
+	if h.rateLimit == nil {
+		return nil
+	}
+
+	h.rateLimit.mu.Lock()
+	defer h.rateLimit.mu.Unlock()
+
+	rl := h.rateLimit.last
+	h.rateLimit.last = nil
+	return rl
+}
+
+func (h *HCPClient) recordRateLimit(resetAt time.Time) {
+	if h.rateLimit == nil {
+		return
+	}
+
+	h.rateLimit.mu.Lock()
+	defer h.rateLimit.mu.Unlock()
+
+	h.rateLimit.last = &v2.RateLimitDescription{
+		Status:  v2.RateLimitDescription_STATUS_OVERLIMIT,
+		ResetAt: timestamppb.New(resetAt),
+	}
+}
+
+// sendWithRetry sends a request, retrying idempotent ones with jittered exponential backoff
+// while Vault reports itself as temporarily unable to serve them.
+func (h *HCPClient) sendWithRetry(ctx context.Context, method string, req func() (*http.Response, error)) (*http.Response, error) {
+	if method != MethodList && method != http.MethodGet {
+		return req()
+	}
+
+	return h.retry(ctx, req)
+}
+
+// retry sends a request and sends it again, with jittered exponential backoff, while Vault reports itself as
+// temporarily unable to serve it. Callers make sure the request can be sent more than once.
+func (h *HCPClient) retry(ctx context.Context, req func() (*http.Response, error)) (*http.Response, error) {
+	l := ctxzap.Extract(ctx)
+	resp, err := req()
+	for attempt := 0; attempt < h.maxRetries && isRetryable(resp, err); attempt++ {
+		wait := retryDelay(resp, attempt)
+
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+
+		// Sealed, standby or failing nodes are retried as well, but only a 429 means the requests are throttled.
+		if statusCode == http.StatusTooManyRequests {
+			h.recordRateLimit(time.Now().Add(wait))
+		}
+
+		l.Warn("vault is unavailable, retrying request",
+			zap.Int("status_code", statusCode),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("wait", wait),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, ctx.Err()
+		case <-timer.C:
+		}
+
+		resp, err = req()
+	}
+
+	return resp, err
+}
+
+// isRetryable reports whether a failed request hit a sealed, standby, rate limited or leaderless node.
+func isRetryable(resp *http.Response, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if resp == nil {
+		return status.Code(err) == codes.Unavailable
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// retryDelay honors the Retry-After header and otherwise backs off exponentially with jitter.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			return min(retryAfter, retryMaxDelay)
+		}
+	}
+
+	backoff := retryMaxDelay
+	if attempt < 10 {
+		backoff = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)) //nolint:gosec // jitter does not need a secure source
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryable(t *testing.T) {
+	failed := errors.New("failed")
+	testCases := []struct {
+		statusCode int
+		err        error
+		retryable  bool
+	}{
+		{http.StatusOK, nil, false},
+		{http.StatusTooManyRequests, failed, true},
+		{http.StatusInternalServerError, failed, true},
+		{http.StatusBadGateway, failed, true},
+		{http.StatusServiceUnavailable, failed, true},
+		{http.StatusGatewayTimeout, failed, true},
+		{http.StatusForbidden, failed, false},
+		{http.StatusNotFound, failed, false},
+	}
+	for _, tc := range testCases {
+		resp := &http.Response{StatusCode: tc.statusCode}
+		require.Equal(t, tc.retryable, isRetryable(resp, tc.err), tc.statusCode)
+	}
+
+	require.True(t, isRetryable(nil, status.Error(codes.Unavailable, "connection refused")))
+	require.False(t, isRetryable(nil, status.Error(codes.DeadlineExceeded, "timeout")))
+	require.False(t, isRetryable(nil, failed))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	require.Equal(t, time.Duration(0), parseRetryAfter(""))
+	require.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+	require.Equal(t, 7*time.Second, parseRetryAfter("7"))
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	wait := parseRetryAfter(date)
+	require.Greater(t, wait, 58*time.Second)
+	require.LessOrEqual(t, wait, time.Minute)
+}
+
+func TestRetryDelay(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	require.Equal(t, 3*time.Second, retryDelay(resp, 0))
+
+	resp.Header.Set("Retry-After", "3600")
+	require.Equal(t, retryMaxDelay, retryDelay(resp, 0))
+
+	for attempt := 0; attempt < 20; attempt++ {
+		backoff := retryMaxDelay
+		if attempt < 10 {
+			backoff = min(retryBaseDelay<<attempt, retryMaxDelay)
+		}
+
+		wait := retryDelay(nil, attempt)
+		require.GreaterOrEqual(t, wait, backoff/2, attempt)
+		require.LessOrEqual(t, wait, backoff, attempt)
+	}
+}
+
+func TestRecordsRateLimitOnlyWhenThrottled(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		throttled  bool
+	}{
+		{http.StatusTooManyRequests, true},
+		{http.StatusServiceUnavailable, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, tc := range testCases {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/auth/token/lookup-self" {
+				writeJSON(w, map[string]any{"data": map[string]any{"ttl": 0}})
+				return
+			}
+
+			if requests.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(tc.statusCode)
+				return
+			}
+
+			writeJSON(w, map[string]any{"data": map[string]any{"name": "default", "policy": ""}})
+		}))
+
+		cli := NewClient()
+		require.Nil(t, cli.WithAddress(server.URL))
+		cli.WithBearerToken("static")
+		cli.WithMaxRetries(1)
+		cli, err := New(context.Background(), cli)
+		require.Nil(t, err)
+
+		syncer := cli.WithOwnRateLimit()
+		_, err = syncer.Uncached().GetACLPolicy(context.Background(), "default")
+		require.Nil(t, err, tc.statusCode)
+		require.EqualValues(t, 2, requests.Load(), tc.statusCode)
+
+		require.Equal(t, tc.throttled, syncer.TakeRateLimit() != nil, tc.statusCode)
+		require.Nil(t, syncer.TakeRateLimit(), tc.statusCode)
+		require.Nil(t, cli.TakeRateLimit(), tc.statusCode)
+
+		server.Close()
+	}
+}
+
+func TestWrapTransportError(t *testing.T) {
+	timeout := &url.Error{Op: "Get", URL: "https://vault", Err: context.DeadlineExceeded}
+	require.Equal(t, codes.DeadlineExceeded, status.Code(wrapTransportError(timeout)))
+
+	refused := errors.New("connection refused")
+	require.Equal(t, refused, wrapTransportError(refused))
+}
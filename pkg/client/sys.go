@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	HealthEndpoint           = "v1/sys/health"
+	SealStatusEndpoint       = "v1/sys/seal-status"
+	CapabilitiesSelfEndpoint = "v1/sys/capabilities-self"
+	CapabilityRoot           = "root"
+	CapabilityDeny           = "deny"
+)
+
+// GetHealth returns the health of the node the client talks to. Standby, sealed and uninitialized
+// nodes are reported in the response instead of through the status code.
+// https://developer.hashicorp.com/vault/api-docs/system/health#read-health-information
+func (h *HCPClient) GetHealth(ctx context.Context) (*HealthAPIData, error) {
+	healthUrl, err := url.JoinPath(h.baseUrl, HealthEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(healthUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	query := uri.Query()
+	for _, code := range []string{"standbycode", "perfstandbycode", "sealedcode", "uninitcode", "drsecondarycode"} {
+		query.Set(code, "200")
+	}
+	uri.RawQuery = query.Encode()
+
+	var res *HealthAPIData
+	// Health and seal status are only served by the root namespace.
+	err = h.Namespace("").getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetSealStatus. Check the seal status of the Vault.
+// https://developer.hashicorp.com/vault/api-docs/system/seal-status
+func (h *HCPClient) GetSealStatus(ctx context.Context) (*SealStatusAPIData, error) {
+	sealUrl, err := url.JoinPath(h.baseUrl, SealStatusEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(sealUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *SealStatusAPIData
+	err = h.Namespace("").getAPIData(ctx,
+		http.MethodGet,
+		uri,
+		&res,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetCapabilitiesSelf returns the capabilities the client token has on each path, relative to the client namespace.
+// https://developer.hashicorp.com/vault/api-docs/system/capabilities-self
+func (h *HCPClient) GetCapabilitiesSelf(ctx context.Context, paths []string) (map[string][]string, error) {
+	capabilitiesUrl, err := url.JoinPath(h.baseUrl, CapabilitiesSelfEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var res capabilitiesAPIData
+	err = h.doRequest(ctx, http.MethodPost, capabilitiesUrl, &res, bodyCapabilities{
+		Paths: paths,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		capabilities[path] = res.Data[path]
+	}
+
+	return capabilities, nil
+}
+
+// HasCapability reports whether a capabilities-self answer grants capability, taking root and deny into account.
+func HasCapability(capabilities []string, capability string) bool {
+	has := false
+	for _, c := range capabilities {
+		switch c {
+		case CapabilityDeny:
+			return false
+		case CapabilityRoot, capability:
+			has = true
+		}
+	}
+
+	return has
+}
+
+// isReadOnlyPost reports whether a POST request only reads from Vault and may be sent by a read-only client.
+func isReadOnlyPost(method string, urlAddress *url.URL) bool {
+	return method == http.MethodPost && (strings.HasSuffix(urlAddress.Path, CapabilitiesSelfEndpoint) ||
+		strings.HasSuffix(urlAddress.Path, LookupEntityEndpoint))
+}
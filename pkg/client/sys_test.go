@@ -0,0 +1,25 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasCapability(t *testing.T) {
+	testCases := []struct {
+		capabilities []string
+		capability   string
+		has          bool
+	}{
+		{[]string{"read", "list"}, "list", true},
+		{[]string{"read"}, "list", false},
+		{[]string{"root"}, "list", true},
+		{[]string{"deny"}, "read", false},
+		{[]string{"list", "deny"}, "list", false},
+		{nil, "read", false},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.has, HasCapability(tc.capabilities, tc.capability), tc.capabilities)
+	}
+}
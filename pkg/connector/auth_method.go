@@ -24,12 +24,18 @@ func (a *authMethodBuilder) List(ctx context.Context, parentResourceID *v2.Resou
 		rv  []*v2.Resource
 	)
 
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	namespace := namespacePath(parentResourceID.Resource)
+	cli := a.client.Namespace(namespace)
 	bag, _, err := getToken(pToken, authMethodResourceType)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	authMethods, nextPageToken, err := a.client.ListAllAuthenticationMethods(ctx)
+	authMethods, nextPageToken, err := cli.ListAllAuthenticationMethods(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -41,9 +47,9 @@ func (a *authMethodBuilder) List(ctx context.Context, parentResourceID *v2.Resou
 
 	for method := range authMethods.Data {
 		ur, err := authMethodResource(ctx, &client.APIResource{
-			ID:   removeTrailingSlash(method),
+			ID:   client.QualifyID(namespace, removeTrailingSlash(method)),
 			Name: removeTrailingSlash(method),
-		}, nil)
+		}, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -55,7 +61,7 @@ func (a *authMethodBuilder) List(ctx context.Context, parentResourceID *v2.Resou
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
+	return rv, nextPageToken, rateLimitAnnotations(a.client), nil
 }
 
 func (a *authMethodBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
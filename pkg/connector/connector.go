@@ -2,7 +2,10 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 
 	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -12,18 +15,22 @@ import (
 
 type Connector struct {
 	client *client.HCPClient
+	cache  *syncCache
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(d.client),
-		newRoleBuilder(d.client),
-		newPolicyBuilder(d.client),
-		newSecretBuilder(d.client),
-		newAuthMethodBuilder(d.client),
-		newGroupBuilder(d.client),
-		newEntityBuilder(d.client),
+		newNamespaceBuilder(d.client.WithOwnRateLimit()),
+		newUserBuilder(d.client.WithOwnRateLimit()),
+		newRoleBuilder(d.client.WithOwnRateLimit(), d.cache),
+		newPolicyBuilder(d.client.WithOwnRateLimit(), d.cache),
+		newSecretEngineBuilder(d.client.WithOwnRateLimit()),
+		newSecretFolderBuilder(d.client.WithOwnRateLimit(), d.cache),
+		newSecretBuilder(d.client.WithOwnRateLimit(), d.cache),
+		newAuthMethodBuilder(d.client.WithOwnRateLimit()),
+		newGroupBuilder(d.client.WithOwnRateLimit(), d.cache),
+		newEntityBuilder(d.client.WithOwnRateLimit(), d.cache),
 	}
 }
 
@@ -41,16 +48,176 @@ func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error)
 	}, nil
 }
 
+// requiredCapability is a capability a resource syncer needs on a path of every synced namespace.
+type requiredCapability struct {
+	path       string
+	capability string
+}
+
+// requiredCapabilities returns the paths the resource syncers of a namespace list or read, and the identity
+// lookup Grant relies on when the client may write. Vault checks the list capability on the path with a trailing
+// slash. Reads of a user or a role are checked on the first one listed, as the sync reads every one of them.
+func (d *Connector) requiredCapabilities(ctx context.Context, cli *client.HCPClient) ([]requiredCapability, error) {
+	required := []requiredCapability{
+		{path: "sys/policy", capability: "read"},
+		{path: "sys/policies/acl/", capability: "read"},
+		{path: "sys/mounts", capability: "read"},
+		{path: "sys/auth", capability: "read"},
+		{path: "identity/group/id/", capability: "list"},
+		{path: "identity/group/id/", capability: "read"},
+		{path: "identity/entity/id/", capability: "list"},
+		{path: "identity/entity/id/", capability: "read"},
+	}
+	if cli.DiscoversNamespaces() {
+		required = append(required, requiredCapability{path: "sys/namespaces/", capability: "list"})
+	}
+	if !cli.ReadOnly() {
+		required = append(required, requiredCapability{path: "identity/lookup/entity", capability: "update"})
+	}
+
+	userpassMounts, err := cli.ListAuthMounts(ctx, client.UserpassType)
+	if err != nil && !client.IsPermissionDenied(err) {
+		return nil, err
+	}
+
+	for _, mount := range userpassMounts {
+		usersPath := "auth/" + mount.Path + "/users/"
+		required = append(required, requiredCapability{path: usersPath, capability: "list"})
+
+		users, err := cli.GetUsers(ctx, mount.Path)
+		if err != nil && !client.IsPermissionDenied(err) && !client.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil && len(users.Data.Keys) > 0 {
+			required = append(required, requiredCapability{path: usersPath + users.Data.Keys[0], capability: "read"})
+		}
+	}
+
+	approleMounts, err := cli.ListAuthMounts(ctx, client.ApproleType)
+	if err != nil && !client.IsPermissionDenied(err) {
+		return nil, err
+	}
+
+	for _, mount := range approleMounts {
+		rolesPath := "auth/" + mount.Path + "/role/"
+		required = append(required, requiredCapability{path: rolesPath, capability: "list"})
+
+		roles, err := cli.GetRoles(ctx, mount.Path)
+		if err != nil && !client.IsPermissionDenied(err) && !client.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil && len(roles.Data.Keys) > 0 {
+			required = append(required,
+				requiredCapability{path: rolesPath + roles.Data.Keys[0], capability: "read"},
+				requiredCapability{path: rolesPath + roles.Data.Keys[0] + "/role-id", capability: "read"},
+			)
+		}
+	}
+
+	kvMounts, err := cli.ListKVMounts(ctx)
+	if err != nil && !client.IsPermissionDenied(err) {
+		return nil, err
+	}
+
+	for _, mount := range kvMounts {
+		if mount.KVVersion() == 1 {
+			required = append(required, requiredCapability{path: mount.Path + "/", capability: "list"})
+			continue
+		}
+
+		// Secret metadata is read for kv v2, secret values never are.
+		required = append(required,
+			requiredCapability{path: mount.Path + "/metadata/", capability: "list"},
+			requiredCapability{path: mount.Path + "/metadata/", capability: "read"},
+		)
+	}
+
+	return required, nil
+}
+
+// missingCapabilities returns the required capabilities the token lacks in a namespace.
+func (d *Connector) missingCapabilities(ctx context.Context, namespace string) ([]string, error) {
+	cli := d.client.Namespace(namespace)
+	required, err := d.requiredCapabilities(ctx, cli)
+	if err != nil {
+		return nil, fmt.Errorf("hcp-connector: error listing mounts: %w", err)
+	}
+
+	paths := make([]string, 0, len(required))
+	for _, rc := range required {
+		if !slices.Contains(paths, rc.path) {
+			paths = append(paths, rc.path)
+		}
+	}
+
+	capabilities, err := cli.GetCapabilitiesSelf(ctx, paths)
+	if err != nil {
+		return nil, fmt.Errorf("hcp-connector: error checking vault token capabilities: %w", err)
+	}
+
+	var missing []string
+	for _, rc := range required {
+		if !client.HasCapability(capabilities[rc.path], rc.capability) {
+			missing = append(missing, fmt.Sprintf("%s on %s%s", rc.capability, namespace, rc.path))
+		}
+	}
+
+	return missing, nil
+}
+
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
+	// The baton syncer validates the connector before it syncs any resource, so a validation starts a new sync:
+	// what the caches kept during the previous one is read from Vault again.
+	d.client.StartSync()
+	health, err := d.client.GetHealth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("hcp-connector: error checking vault health: %w", err)
+	}
+
+	if !health.Initialized {
+		return nil, fmt.Errorf("hcp-connector: vault is not initialized")
+	}
+
+	sealStatus, err := d.client.GetSealStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("hcp-connector: error checking vault seal status: %w", err)
+	}
+
+	if sealStatus.Sealed {
+		return nil, fmt.Errorf("hcp-connector: vault is sealed, %d of %d unseal keys provided", sealStatus.Progress, sealStatus.T)
+	}
+
+	if _, err := d.client.LookupSelf(ctx); err != nil {
+		return nil, fmt.Errorf("hcp-connector: vault token is not valid: %w", err)
+	}
+
+	namespaces, err := d.client.ListAllNamespaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("hcp-connector: error listing namespaces: %w", err)
+	}
+
+	var missing []string
+	for _, namespace := range namespaces {
+		nsMissing, err := d.missingCapabilities(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, nsMissing...)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("hcp-connector: vault token is missing capabilities: %s", strings.Join(missing, ", "))
+	}
+
 	return nil, nil
 }
 
 // New returns a new instance of the connector.
-func New(ctx context.Context, token, host string, hcpClient *client.HCPClient) (*Connector, error) {
+func New(ctx context.Context, host string, hcpClient *client.HCPClient) (*Connector, error) {
 	var err error
-	if token != "" && host != "" {
+	if host != "" {
 		hcpClient, err = client.New(ctx, hcpClient)
 		if err != nil {
 			return nil, err
@@ -59,5 +226,6 @@ func New(ctx context.Context, token, host string, hcpClient *client.HCPClient) (
 
 	return &Connector{
 		client: hcpClient,
+		cache:  newSyncCache(),
 	}, nil
 }
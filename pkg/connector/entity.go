@@ -2,16 +2,22 @@ package connector
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 )
 
+const aliasEntitlement = "alias"
+
 type entityBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.HCPClient
+	cache        *syncCache
 }
 
 func (e *entityBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -24,12 +30,18 @@ func (e *entityBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		rv  []*v2.Resource
 	)
 
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	namespace := namespacePath(parentResourceID.Resource)
+	cli := e.client.Namespace(namespace)
 	bag, _, err := getToken(pToken, entityResourceType)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	entities, nextPageToken, err := e.client.ListAllEntities(ctx)
+	entities, nextPageToken, err := cli.ListAllEntities(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -40,10 +52,15 @@ func (e *entityBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 	}
 
 	for entityId, entity := range entities.Data.KeyInfo {
+		entityInfo, err := cli.GetEntity(ctx, entityId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
 		ur, err := entityResource(ctx, &client.APIResource{
-			ID:   entityId,
+			ID:   client.QualifyID(namespace, entityId),
 			Name: entity.Name,
-		}, nil)
+		}, &entityInfo.Data, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -56,20 +73,85 @@ func (e *entityBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
+	return rv, nextPageToken, rateLimitAnnotations(e.client), nil
 }
 
+// Entitlements returns the alias entitlement of the entity, held by the userpass users and AppRole roles
+// that log in as the entity.
 func (e *entityBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	var rv []*v2.Entitlement
+	aliasOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType, roleResourceType),
+		ent.WithDescription(fmt.Sprintf("Alias of %s entity", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s entity %s", resource.DisplayName, aliasEntitlement)),
+	}
+	rv = append(rv, ent.NewAssignmentEntitlement(resource, aliasEntitlement, aliasOptions...))
+
+	return rv, "", nil, nil
 }
 
+// Grants links the entity to the userpass users and AppRole roles having an alias in it.
+// Aliases of other auth methods have no resource to link to.
 func (e *entityBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	var rv []*v2.Grant
+	namespace := namespaceOf(resource)
+	cli := e.client.Namespace(namespace)
+	identity, err := e.cache.identity(ctx, e.client, namespace)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	entity, ok := identity.entities[client.UnqualifyID(namespace, resource.Id.Resource)]
+	if !ok {
+		return nil, "", rateLimitAnnotations(e.client), nil
+	}
+
+	for _, alias := range entity.Aliases {
+		var principal *v2.ResourceId
+		switch alias.MountType {
+		case client.UserpassType:
+			mount, err := findAuthMount(ctx, cli, client.UserpassType, alias.MountAccessor)
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			principal = &v2.ResourceId{
+				ResourceType: userResourceType.Id,
+				Resource:     client.QualifyID(namespace, mountQualifiedID(mount.Path, alias.Name)),
+			}
+		case client.ApproleType:
+			mount, err := findAuthMount(ctx, cli, client.ApproleType, alias.MountAccessor)
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			roleName, err := e.cache.approleRoleName(ctx, cli, mount, alias.Name)
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			if roleName == "" {
+				continue
+			}
+
+			principal = &v2.ResourceId{
+				ResourceType: roleResourceType.Id,
+				Resource:     client.QualifyID(namespace, mountQualifiedID(mount.Accessor, roleName)),
+			}
+		default:
+			continue
+		}
+
+		rv = append(rv, grant.NewGrant(resource, aliasEntitlement, principal))
+	}
+
+	return rv, "", rateLimitAnnotations(e.client), nil
 }
 
-func newEntityBuilder(c *client.HCPClient) *entityBuilder {
+func newEntityBuilder(c *client.HCPClient, cache *syncCache) *entityBuilder {
 	return &entityBuilder{
 		resourceType: entityResourceType,
 		client:       c,
+		cache:        cache,
 	}
 }
@@ -0,0 +1,90 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityGrantsLinkAliases(t *testing.T) {
+	vault := newFakeVault()
+	vault.users["userpass"]["alice"] = &client.UserData{}
+	vault.roles["approle"]["app"] = &client.RoleData{}
+	vault.roleIDs["approle"]["app"] = "role-id-app"
+	vault.roles["approle"]["ci"] = &client.RoleData{}
+	vault.roleIDs["approle"]["ci"] = "role-id-ci"
+	vault.entities["e-alice"] = &client.EntityData{
+		ID: "e-alice",
+		Aliases: []client.AliasData{
+			{Name: "alice", MountAccessor: "auth_userpass_1", MountType: client.UserpassType},
+			{Name: "role-id-app", MountAccessor: "auth_approle_1", MountType: client.ApproleType},
+		},
+	}
+	vault.entities["e-ci"] = &client.EntityData{
+		ID: "e-ci",
+		Aliases: []client.AliasData{
+			{Name: "role-id-ci", MountAccessor: "auth_approle_1", MountType: client.ApproleType},
+			// The role of this alias was deleted.
+			{Name: "role-id-gone", MountAccessor: "auth_approle_1", MountType: client.ApproleType},
+		},
+	}
+
+	c := newTestConnector(t, vault)
+	e := newEntityBuilder(c.client, c.cache)
+	principals := func(entityID string) map[string]string {
+		rv := map[string]string{}
+		for _, g := range allGrants(t, e, &v2.Resource{
+			Id:               &v2.ResourceId{ResourceType: entityResourceType.Id, Resource: entityID},
+			ParentResourceId: rootNamespaceResourceID,
+		}) {
+			require.True(t, strings.HasSuffix(g.Entitlement.Id, ":"+aliasEntitlement))
+			rv[g.Principal.Id.Resource] = g.Principal.Id.ResourceType
+		}
+
+		return rv
+	}
+
+	require.Equal(t, map[string]string{
+		"userpass/alice": userResourceType.Id,
+		mountQualifiedID("auth_approle_1", "app"): roleResourceType.Id,
+	}, principals("e-alice"))
+	require.Equal(t, map[string]string{
+		mountQualifiedID("auth_approle_1", "ci"): roleResourceType.Id,
+	}, principals("e-ci"))
+
+	// The role IDs are read once for the whole sync, not once per alias.
+	require.Equal(t, 1, vault.requestCount("GET", "auth/approle/role/app/role-id"))
+	require.Equal(t, 1, vault.requestCount("GET", "auth/approle/role/ci/role-id"))
+}
+
+func TestEntityGrantsReadRoleIDsEverySync(t *testing.T) {
+	vault := newFakeVault()
+	vault.roles["approle"]["app"] = &client.RoleData{}
+	vault.roleIDs["approle"]["app"] = "role-id-old"
+	vault.entities["e-app"] = &client.EntityData{
+		ID:      "e-app",
+		Aliases: []client.AliasData{{Name: "role-id-new", MountAccessor: "auth_approle_1", MountType: client.ApproleType}},
+	}
+
+	c := newTestConnector(t, vault)
+	e := newEntityBuilder(c.client, c.cache)
+	entity := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: entityResourceType.Id, Resource: "e-app"},
+		ParentResourceId: rootNamespaceResourceID,
+	}
+	require.Empty(t, allGrants(t, e, entity))
+
+	// The role ID is set to the one of the alias between two syncs.
+	vault.mu.Lock()
+	vault.roleIDs["approle"]["app"] = "role-id-new"
+	vault.mu.Unlock()
+
+	c.client.StartSync()
+	grants := allGrants(t, e, entity)
+	require.Len(t, grants, 1)
+	require.Equal(t, mountQualifiedID("auth_approle_1", "app"), grants[0].Principal.Id.Resource)
+	require.Equal(t, 2, vault.requestCount("GET", "auth/approle/role/app/role-id"))
+}
@@ -2,16 +2,28 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"slices"
 
 	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const memberEntitlement = "member"
+
 type groupBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.HCPClient
+	cache        *syncCache
 }
 
 func (g *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -24,12 +36,18 @@ func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 		rv  []*v2.Resource
 	)
 
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	namespace := namespacePath(parentResourceID.Resource)
+	cli := g.client.Namespace(namespace)
 	bag, _, err := getToken(pToken, groupResourceType)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	groups, nextPageToken, err := g.client.ListAllGroups(ctx)
+	groups, nextPageToken, err := cli.ListAllGroups(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -40,10 +58,15 @@ func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 	}
 
 	for groupId, group := range groups.Data.KeyInfo {
+		groupInfo, err := cli.GetGroup(ctx, groupId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
 		ur, err := groupResource(ctx, &client.APIResource{
-			ID:   groupId,
+			ID:   client.QualifyID(namespace, groupId),
 			Name: group.Name,
-		}, nil)
+		}, &groupInfo.Data, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -55,20 +78,221 @@ func (g *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
+	return rv, nextPageToken, rateLimitAnnotations(g.client), nil
 }
 
+// Entitlements returns the membership of an internal group, held by entities and by nested groups.
+// External groups get their members from their group alias, they have no entitlement.
 func (g *groupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	var rv []*v2.Entitlement
+	if !isInternalGroup(resource) {
+		return nil, "", nil, nil
+	}
+
+	memberOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(entityResourceType, groupResourceType),
+		ent.WithDescription(fmt.Sprintf("Member of %s group", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s group %s", resource.DisplayName, memberEntitlement)),
+	}
+	rv = append(rv, ent.NewAssignmentEntitlement(resource, memberEntitlement, memberOptions...))
+
+	return rv, "", nil, nil
 }
 
+// isInternalGroup reports whether the group_type of a group resource is internal.
+func isInternalGroup(resource *v2.Resource) bool {
+	groupTrait, err := rs.GetGroupTrait(resource)
+	if err != nil {
+		return false
+	}
+
+	groupType, _ := rs.GetProfileStringValue(groupTrait.Profile, "group_type")
+	return groupType == client.GroupTypeInternal
+}
+
+// Grants returns the member entities of an internal group and its member groups. Members of an internal member
+// group are expanded into members of the group.
 func (g *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	var rv []*v2.Grant
+	namespace := namespaceOf(resource)
+	identity, err := g.cache.identity(ctx, g.client, namespace)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	group, ok := identity.groups[client.UnqualifyID(namespace, resource.Id.Resource)]
+	if !ok || group.Type != client.GroupTypeInternal {
+		return nil, "", rateLimitAnnotations(g.client), nil
+	}
+
+	for _, entityId := range group.MemberEntityIDs {
+		rv = append(rv, grant.NewGrant(resource, memberEntitlement, &v2.ResourceId{
+			ResourceType: entityResourceType.Id,
+			Resource:     client.QualifyID(namespace, entityId),
+		}))
+	}
+
+	for _, groupId := range group.MemberGroupIDs {
+		memberGroup, ok := identity.groups[groupId]
+		if !ok {
+			continue
+		}
+
+		memberGroupID := &v2.ResourceId{
+			ResourceType: groupResourceType.Id,
+			Resource:     client.QualifyID(namespace, groupId),
+		}
+		rv = append(rv, grant.NewGrant(resource, memberEntitlement, memberGroupID, groupMemberExpansion(memberGroupID, memberGroup)...))
+	}
+
+	return rv, "", rateLimitAnnotations(g.client), nil
+}
+
+// groupMemberExpansion expands a grant to an internal group into grants to the members of the group.
+func groupMemberExpansion(groupID *v2.ResourceId, data *client.GroupData) []grant.GrantOption {
+	if data.Type != client.GroupTypeInternal {
+		return nil
+	}
+
+	return []grant.GrantOption{grant.WithAnnotation(&v2.GrantExpandable{
+		EntitlementIds: []string{ent.NewEntitlementID(&v2.Resource{Id: groupID}, memberEntitlement)},
+	})}
+}
+
+// Grant adds an entity to the member entities, or a group to the member groups, of an internal group.
+func (g *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	updated, err := g.updateMembers(ctx, entitlement.Resource.Id, principal.Id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	return nil, nil
+}
+
+// Revoke removes the principal of the grant from the members of an internal group.
+func (g *groupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	updated, err := g.updateMembers(ctx, grant.Entitlement.Resource.Id, grant.Principal.Id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	return nil, nil
+}
+
+// updateMembers adds or removes a member of a group with a read-modify-write of the group, then reads the group
+// again to check the members are the ones written. It reports whether the membership had to change. Vault has no
+// check-and-set on groups: when another writer updated the group around our write, one of the two changes may be
+// lost, and the update fails with codes.Aborted instead of writing again over the other writer.
+func (g *groupBuilder) updateMembers(ctx context.Context, groupResourceID, principal *v2.ResourceId, add bool) (bool, error) {
+	l := ctxzap.Extract(ctx)
+	namespace, groupId, err := g.client.SplitNamespacedID(ctx, groupResourceID.Resource)
+	if err != nil {
+		return false, err
+	}
+
+	principalNamespace, memberId, err := g.client.SplitNamespacedID(ctx, principal.Resource)
+	if err != nil {
+		return false, err
+	}
+
+	if principalNamespace != namespace {
+		return false, status.Errorf(codes.InvalidArgument,
+			"hcp-connector: %s %s is not in the namespace of the group", principal.ResourceType, principal.Resource)
+	}
+
+	if principal.ResourceType != entityResourceType.Id && principal.ResourceType != groupResourceType.Id {
+		return false, status.Errorf(codes.Unimplemented,
+			"hcp-connector: %s principals cannot be group members", principal.ResourceType)
+	}
+
+	// Reads go around the response cache, it would hand back the members as they were before the write.
+	cli := g.client.Namespace(namespace).Uncached()
+	members, err := groupMembers(ctx, cli, groupId, principal.ResourceType)
+	if err != nil {
+		return false, err
+	}
+
+	if slices.Contains(members, memberId) == add {
+		return false, nil
+	}
+
+	if add {
+		members = append(members, memberId)
+	} else {
+		members = slices.DeleteFunc(members, func(id string) bool {
+			return id == memberId
+		})
+	}
+
+	if principal.ResourceType == entityResourceType.Id {
+		err = cli.UpdateGroupMemberEntities(ctx, members, groupId)
+	} else {
+		err = cli.UpdateGroupMemberGroups(ctx, members, groupId)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	written, err := groupMembers(ctx, cli, groupId, principal.ResourceType)
+	if err != nil {
+		return false, err
+	}
+
+	if sameMembers(written, members) {
+		return true, nil
+	}
+
+	l.Warn("group membership was changed concurrently",
+		zap.String("group_id", groupResourceID.Resource),
+		zap.String("member_id", principal.Resource),
+		zap.Bool("member", slices.Contains(written, memberId)),
+	)
+
+	return false, status.Errorf(codes.Aborted,
+		"hcp-connector: members of group %s were changed by another writer during the update, Vault has no "+
+			"check-and-set on groups so either change may have been lost: check the members of the group and retry",
+		groupResourceID.Resource)
+}
+
+// sameMembers reports whether two member lists hold the same IDs, in any order.
+func sameMembers(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+// groupMembers returns the member entities or the member groups of an internal group.
+func groupMembers(ctx context.Context, cli *client.HCPClient, groupId, memberType string) ([]string, error) {
+	groupInfo, err := cli.GetGroup(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupInfo.Data.Type == client.GroupTypeExternal {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"hcp-connector: members of external group %s come from its group alias", groupInfo.Data.Name)
+	}
+
+	if memberType == entityResourceType.Id {
+		return groupInfo.Data.MemberEntityIDs, nil
+	}
+
+	return groupInfo.Data.MemberGroupIDs, nil
 }
 
-func newGroupBuilder(c *client.HCPClient) *groupBuilder {
+func newGroupBuilder(c *client.HCPClient, cache *syncCache) *groupBuilder {
 	return &groupBuilder{
 		resourceType: groupResourceType,
 		client:       c,
+		cache:        cache,
 	}
 }
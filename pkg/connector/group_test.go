@@ -0,0 +1,165 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var rootNamespaceResourceID = &v2.ResourceId{ResourceType: namespaceResourceType.Id, Resource: rootNamespaceID}
+
+func testGroupResource(t *testing.T, data *client.GroupData) *v2.Resource {
+	resource, err := groupResource(ctxTest, &client.APIResource{ID: data.ID, Name: data.Name}, data, rootNamespaceResourceID)
+	require.Nil(t, err)
+
+	return resource
+}
+
+func TestGroupMemberEntitlementInternalOnly(t *testing.T) {
+	g := newGroupBuilder(nil, nil)
+	internal := testGroupResource(t, &client.GroupData{ID: "g-1", Name: "admins", Type: client.GroupTypeInternal})
+	external := testGroupResource(t, &client.GroupData{ID: "g-2", Name: "okta-admins", Type: client.GroupTypeExternal})
+
+	entitlements, _, _, err := g.Entitlements(ctxTest, internal, &pagination.Token{})
+	require.Nil(t, err)
+	require.Len(t, entitlements, 1)
+
+	entitlements, _, _, err = g.Entitlements(ctxTest, external, &pagination.Token{})
+	require.Nil(t, err)
+	require.Empty(t, entitlements)
+}
+
+func TestPolicyGroupGrantsExpandToMembers(t *testing.T) {
+	vault := newFakeVault()
+	vault.policies["ops"] = ""
+	vault.groups["g-1"] = &client.GroupData{ID: "g-1", Name: "admins", Type: client.GroupTypeInternal, Policies: []string{"ops"}}
+	vault.groups["g-2"] = &client.GroupData{ID: "g-2", Name: "okta-admins", Type: client.GroupTypeExternal, Policies: []string{"ops"}}
+
+	c := newTestConnector(t, vault)
+	policy := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: policyResourceType.Id, Resource: "ops"},
+		ParentResourceId: rootNamespaceResourceID,
+	}
+
+	expanded := map[string]bool{}
+	for _, g := range allGrants(t, newPolicyBuilder(c.client, c.cache), policy) {
+		if g.Principal.Id.ResourceType != groupResourceType.Id {
+			continue
+		}
+
+		annos := annotations.Annotations(g.Annotations)
+		expandable := &v2.GrantExpandable{}
+		ok, err := annos.Pick(expandable)
+		require.Nil(t, err)
+		expanded[g.Principal.Id.Resource] = ok
+		if ok {
+			require.Equal(t, []string{"group:g-1:member"}, expandable.EntitlementIds)
+		}
+	}
+
+	require.Equal(t, map[string]bool{"g-1": true, "g-2": false}, expanded)
+}
+
+func TestGroupGrantRevokeMember(t *testing.T) {
+	vault := newFakeVault()
+	vault.groups["g-1"] = &client.GroupData{ID: "g-1", Name: "admins", Type: client.GroupTypeInternal, MemberEntityIDs: []string{"e-bob"}}
+	vault.groups["g-2"] = &client.GroupData{ID: "g-2", Name: "ops", Type: client.GroupTypeInternal}
+
+	c := newTestConnector(t, vault)
+	g := newGroupBuilder(c.client, c.cache)
+	group := testGroupResource(t, vault.groups["g-1"])
+	member := &v2.Entitlement{Resource: group}
+	alice := &v2.Resource{Id: &v2.ResourceId{ResourceType: entityResourceType.Id, Resource: "e-alice"}}
+	ops := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "g-2"}}
+
+	annos, err := g.Grant(ctxTest, alice, member)
+	require.Nil(t, err)
+	require.Empty(t, annos)
+	require.ElementsMatch(t, []string{"e-bob", "e-alice"}, vault.groups["g-1"].MemberEntityIDs)
+
+	annos, err = g.Grant(ctxTest, alice, member)
+	require.Nil(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+
+	_, err = g.Grant(ctxTest, ops, member)
+	require.Nil(t, err)
+	require.Equal(t, []string{"g-2"}, vault.groups["g-1"].MemberGroupIDs)
+
+	annos, err = g.Revoke(ctxTest, &v2.Grant{Entitlement: member, Principal: alice})
+	require.Nil(t, err)
+	require.Empty(t, annos)
+	require.Equal(t, []string{"e-bob"}, vault.groups["g-1"].MemberEntityIDs)
+
+	annos, err = g.Revoke(ctxTest, &v2.Grant{Entitlement: member, Principal: alice})
+	require.Nil(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+}
+
+func TestGroupGrantMemberConcurrentChange(t *testing.T) {
+	vault := newFakeVault()
+	vault.groups["g-1"] = &client.GroupData{ID: "g-1", Name: "admins", Type: client.GroupTypeInternal, MemberEntityIDs: []string{"e-bob"}}
+
+	// Another writer replaces the members right after the write of the connector.
+	vault.afterWrite = func(path string) {
+		vault.mu.Lock()
+		defer vault.mu.Unlock()
+
+		vault.groups["g-1"].MemberEntityIDs = []string{"e-bob", "e-carol"}
+	}
+
+	c := newTestConnector(t, vault)
+	g := newGroupBuilder(c.client, c.cache)
+	alice := &v2.Resource{Id: &v2.ResourceId{ResourceType: entityResourceType.Id, Resource: "e-alice"}}
+	_, err := g.Grant(ctxTest, alice, &v2.Entitlement{Resource: testGroupResource(t, vault.groups["g-1"])})
+
+	// The change is reported instead of being written again over the other writer.
+	require.Equal(t, codes.Aborted, status.Code(err))
+	require.ErrorContains(t, err, "check-and-set")
+	require.Equal(t, 1, vault.requestCount("POST", "identity/group/id/g-1"))
+	require.Equal(t, []string{"e-bob", "e-carol"}, vault.groups["g-1"].MemberEntityIDs)
+}
+
+func TestGroupGrantExternalGroup(t *testing.T) {
+	vault := newFakeVault()
+	vault.groups["g-1"] = &client.GroupData{ID: "g-1", Name: "okta-admins", Type: client.GroupTypeExternal}
+
+	c := newTestConnector(t, vault)
+	g := newGroupBuilder(c.client, c.cache)
+	alice := &v2.Resource{Id: &v2.ResourceId{ResourceType: entityResourceType.Id, Resource: "e-alice"}}
+	_, err := g.Grant(ctxTest, alice, &v2.Entitlement{Resource: testGroupResource(t, vault.groups["g-1"])})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, 0, vault.requestCount("POST", "identity/group/id/g-1"))
+}
+
+func TestExternalGroupExternalID(t *testing.T) {
+	oidc := testGroupResource(t, &client.GroupData{
+		ID:    "g-1",
+		Name:  "oidc-engineering",
+		Type:  client.GroupTypeExternal,
+		Alias: client.AliasData{Name: "engineering", MountAccessor: "auth_oidc_1", MountType: "oidc"},
+	})
+	ldap := testGroupResource(t, &client.GroupData{
+		ID:    "g-2",
+		Name:  "ldap-engineering",
+		Type:  client.GroupTypeExternal,
+		Alias: client.AliasData{Name: "engineering", MountAccessor: "auth_ldap_1", MountType: "ldap"},
+	})
+
+	// The external ID is the name of the group in the identity provider, the alias mount is in the profile.
+	require.Equal(t, "engineering", oidc.ExternalId.Id)
+	require.Equal(t, "engineering", ldap.ExternalId.Id)
+
+	trait, err := rs.GetGroupTrait(ldap)
+	require.Nil(t, err)
+	accessor, _ := rs.GetProfileStringValue(trait.Profile, "alias_mount_accessor")
+	require.Equal(t, "auth_ldap_1", accessor)
+	mountType, _ := rs.GetProfileStringValue(trait.Profile, "alias_mount_type")
+	require.Equal(t, "ldap", mountType)
+}
@@ -2,11 +2,16 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"path"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 )
@@ -14,9 +19,11 @@ import (
 func userResource(ctx context.Context, user *client.APIResource, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	var userStatus v2.UserTrait_Status_Status = v2.UserTrait_Status_STATUS_ENABLED
 	profile := map[string]interface{}{
-		"user_id":    user.ID,
-		"user_name":  user.Name,
-		"mount_type": user.MountType,
+		"user_id":        user.ID,
+		"user_name":      user.Name,
+		"mount_type":     user.MountType,
+		"mount_path":     user.MountPath,
+		"mount_accessor": user.MountAccessor,
 	}
 
 	userTraits := []rs.UserTraitOption{
@@ -37,18 +44,36 @@ func userResource(ctx context.Context, user *client.APIResource, parentResourceI
 	return ret, nil
 }
 
-func roleResource(ctx context.Context, role *client.APIResource, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+func roleResource(ctx context.Context, role *client.APIResource, roleID string, config *client.RoleData, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"id":         role.ID,
-		"name":       role.Name,
-		"mount_type": role.MountType,
+		"id":                    role.ID,
+		"name":                  role.Name,
+		"mount_type":            role.MountType,
+		"mount_path":            role.MountPath,
+		"mount_accessor":        role.MountAccessor,
+		"role_id":               roleID,
+		"token_policies":        strings.Join(config.TokenPolicies, ","),
+		"token_type":            config.TokenType,
+		"token_ttl":             config.TokenTTL,
+		"token_max_ttl":         config.TokenMaxTTL,
+		"token_bound_cidrs":     strings.Join(config.TokenBoundCidrs, ","),
+		"bind_secret_id":        config.BindSecretID,
+		"secret_id_ttl":         config.SecretIDTTL,
+		"secret_id_num_uses":    config.SecretIDNumUses,
+		"secret_id_bound_cidrs": strings.Join(config.SecretIDBoundCidrs, ","),
 	}
 
 	roleTraitOptions := []rs.RoleTraitOption{
 		rs.WithRoleProfile(profile),
 	}
 
-	resource, err := rs.NewRoleResource(role.Name, roleResourceType, role.ID, roleTraitOptions)
+	resource, err := rs.NewRoleResource(
+		role.Name,
+		roleResourceType,
+		role.ID,
+		roleTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -56,18 +81,19 @@ func roleResource(ctx context.Context, role *client.APIResource, parentResourceI
 	return resource, nil
 }
 
-func policyResource(ctx context.Context, policy *client.APIResource, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+func policyResource(ctx context.Context, policy *client.APIResource, rules []client.ACLRule, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	var opts []rs.ResourceOption
 	profile := map[string]interface{}{
 		"id":         policy.ID,
 		"name":       policy.Name,
 		"mount_type": policy.MountType,
+		"rules":      aclRulesProfile(rules),
 	}
 
 	policyTraitOptions := []rs.AppTraitOption{
 		rs.WithAppProfile(profile),
 	}
-	opts = append(opts, rs.WithAppTrait(policyTraitOptions...))
+	opts = append(opts, rs.WithAppTrait(policyTraitOptions...), rs.WithParentResourceID(parentResourceID))
 	resource, err := rs.NewResource(
 		policy.Name,
 		policyResourceType,
@@ -81,18 +107,59 @@ func policyResource(ctx context.Context, policy *client.APIResource, parentResou
 	return resource, nil
 }
 
-func secretResource(ctx context.Context, secret *client.APIResource, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+// aclRulesProfile turns policy rules into profile values.
+func aclRulesProfile(rules []client.ACLRule) []interface{} {
+	profile := make([]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		capabilities := make([]interface{}, 0, len(rule.Capabilities))
+		for _, capability := range rule.Capabilities {
+			capabilities = append(capabilities, capability)
+		}
+
+		requiredParameters := make([]interface{}, 0, len(rule.RequiredParameters))
+		for _, parameter := range rule.RequiredParameters {
+			requiredParameters = append(requiredParameters, parameter)
+		}
+
+		profile = append(profile, map[string]interface{}{
+			"path":                rule.Path,
+			"capabilities":        capabilities,
+			"allowed_parameters":  parametersProfile(rule.AllowedParameters),
+			"denied_parameters":   parametersProfile(rule.DeniedParameters),
+			"required_parameters": requiredParameters,
+			"min_wrapping_ttl":    int64(rule.MinWrappingTTL.Seconds()),
+			"max_wrapping_ttl":    int64(rule.MaxWrappingTTL.Seconds()),
+		})
+	}
+
+	return profile
+}
+
+func parametersProfile(parameters map[string][]interface{}) map[string]interface{} {
+	profile := make(map[string]interface{}, len(parameters))
+	for key, values := range parameters {
+		profile[key] = values
+	}
+
+	return profile
+}
+
+func secretResource(ctx context.Context, secret *client.APIResource, metadata *client.SecretMetadata, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	var opts []rs.ResourceOption
 	profile := map[string]interface{}{
 		"id":         secret.ID,
 		"name":       secret.Name,
 		"mount_type": secret.MountType,
+		"mount_path": secret.MountPath,
+	}
+	if metadata != nil {
+		addSecretMetadata(profile, metadata)
 	}
 
 	policyTraitOptions := []rs.AppTraitOption{
 		rs.WithAppProfile(profile),
 	}
-	opts = append(opts, rs.WithAppTrait(policyTraitOptions...))
+	opts = append(opts, rs.WithAppTrait(policyTraitOptions...), rs.WithParentResourceID(parentResourceID))
 	resource, err := rs.NewResource(
 		secret.Name,
 		secretResourceType,
@@ -106,10 +173,123 @@ func secretResource(ctx context.Context, secret *client.APIResource, parentResou
 	return resource, nil
 }
 
-func groupResource(ctx context.Context, group *client.APIResource, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+func secretEngineResource(ctx context.Context, namespace string, mount client.SecretsMount, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	var opts []rs.ResourceOption
+	profile := map[string]interface{}{
+		"id":          client.QualifyID(namespace, mount.Path),
+		"path":        mount.Path,
+		"type":        mount.Type,
+		"version":     mount.KVVersion(),
+		"accessor":    mount.Accessor,
+		"description": mount.Description,
+	}
+
+	engineTraitOptions := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+	opts = append(opts,
+		rs.WithAppTrait(engineTraitOptions...),
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: secretFolderResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: secretResourceType.Id},
+		),
+	)
+	resource, err := rs.NewResource(
+		mount.Path,
+		secretEngineResourceType,
+		client.QualifyID(namespace, mount.Path),
+		opts...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+func secretFolderResource(ctx context.Context, folder *client.APIResource, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	var opts []rs.ResourceOption
+	profile := map[string]interface{}{
+		"id":         folder.ID,
+		"name":       folder.Name,
+		"mount_type": folder.MountType,
+		"mount_path": folder.MountPath,
+	}
+
+	folderTraitOptions := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+	opts = append(opts,
+		rs.WithAppTrait(folderTraitOptions...),
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: secretFolderResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: secretResourceType.Id},
+		),
+	)
+	resource, err := rs.NewResource(
+		folder.Name,
+		secretFolderResourceType,
+		folder.ID,
+		opts...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// addSecretMetadata records the age, the version history and the custom metadata of a kv v2 secret.
+func addSecretMetadata(profile map[string]interface{}, metadata *client.SecretMetadata) {
+	versions := make(map[string]interface{}, len(metadata.Versions))
+	for version, info := range metadata.Versions {
+		versions[version] = map[string]interface{}{
+			"created_time":  info.CreatedTime,
+			"deletion_time": info.DeletionTime,
+			"deleted":       info.DeletionTime != "",
+			"destroyed":     info.Destroyed,
+		}
+	}
+
+	customMetadata := make(map[string]interface{}, len(metadata.CustomMetadata))
+	for key, value := range metadata.CustomMetadata {
+		customMetadata[key] = value
+	}
+
+	profile["created_time"] = metadata.CreatedTime
+	profile["updated_time"] = metadata.UpdatedTime
+	profile["current_version"] = metadata.CurrentVersion
+	profile["oldest_version"] = metadata.OldestVersion
+	profile["max_versions"] = metadata.MaxVersions
+	profile["delete_version_after"] = metadata.DeleteVersionAfter
+	profile["versions"] = versions
+	profile["custom_metadata"] = customMetadata
+}
+
+// groupResource builds a group resource. External groups get their members from a group alias, the alias name
+// is their external ID so they can be matched with the group of the identity provider.
+func groupResource(ctx context.Context, group *client.APIResource, data *client.GroupData, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"group_name": group.Name,
 		"group_id":   group.ID,
+		"group_type": data.Type,
+	}
+
+	options := []rs.ResourceOption{
+		rs.WithParentResourceID(parentResourceID),
+	}
+	if data.Alias.Name != "" {
+		profile["alias_name"] = data.Alias.Name
+		profile["alias_mount_accessor"] = data.Alias.MountAccessor
+		profile["alias_mount_type"] = data.Alias.MountType
+		profile["alias_mount_path"] = data.Alias.MountPath
+		// The alias name is the name of the group in the identity provider, the mount is kept in the profile.
+		options = append(options, rs.WithExternalID(&v2.ExternalId{
+			Id:          data.Alias.Name,
+			Description: fmt.Sprintf("%s group alias on %s", data.Alias.MountType, data.Alias.MountAccessor),
+		}))
 	}
 
 	groupTraitOptions := []rs.GroupTraitOption{
@@ -121,6 +301,7 @@ func groupResource(ctx context.Context, group *client.APIResource, parentResourc
 		groupResourceType,
 		group.ID,
 		groupTraitOptions,
+		options...,
 	)
 
 	if err != nil {
@@ -185,7 +366,7 @@ func authMethodResource(ctx context.Context, secret *client.APIResource, parentR
 	policyTraitOptions := []rs.AppTraitOption{
 		rs.WithAppProfile(profile),
 	}
-	opts = append(opts, rs.WithAppTrait(policyTraitOptions...))
+	opts = append(opts, rs.WithAppTrait(policyTraitOptions...), rs.WithParentResourceID(parentResourceID))
 	resource, err := rs.NewResource(
 		secret.Name,
 		authMethodResourceType,
@@ -199,21 +380,95 @@ func authMethodResource(ctx context.Context, secret *client.APIResource, parentR
 	return resource, nil
 }
 
-func entityResource(ctx context.Context, entity *client.APIResource, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
-	var opts []rs.ResourceOption
+// entityResource builds an entity as a user: a disabled entity is a disabled user, its metadata and aliases
+// go in the profile and the names of its aliases are its logins.
+func entityResource(ctx context.Context, entity *client.APIResource, data *client.EntityData, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	metadata := make(map[string]interface{}, len(data.Metadata))
+	for key, value := range data.Metadata {
+		metadata[key] = value
+	}
+
+	var (
+		aliases []interface{}
+		logins  []string
+	)
+	for _, alias := range data.Aliases {
+		aliases = append(aliases, map[string]interface{}{
+			"id":             alias.ID,
+			"name":           alias.Name,
+			"mount_accessor": alias.MountAccessor,
+			"mount_type":     alias.MountType,
+			"mount_path":     alias.MountPath,
+		})
+		if !slices.Contains(logins, alias.Name) && alias.Name != entity.Name {
+			logins = append(logins, alias.Name)
+		}
+	}
+
 	profile := map[string]interface{}{
-		"id":   entity.ID,
-		"name": entity.Name,
+		"id":       entity.ID,
+		"name":     entity.Name,
+		"disabled": data.Disabled,
+		"metadata": metadata,
+		"aliases":  aliases,
 	}
 
-	policyTraitOptions := []rs.AppTraitOption{
-		rs.WithAppProfile(profile),
+	entityStatus := v2.UserTrait_Status_STATUS_ENABLED
+	if data.Disabled {
+		entityStatus = v2.UserTrait_Status_STATUS_DISABLED
 	}
-	opts = append(opts, rs.WithAppTrait(policyTraitOptions...))
-	resource, err := rs.NewResource(
+
+	userTraits := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithStatus(entityStatus),
+		rs.WithUserLogin(entity.Name, logins...),
+	}
+
+	resource, err := rs.NewUserResource(
 		entity.Name,
 		entityResourceType,
 		entity.ID,
+		userTraits,
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+func namespaceResource(ctx context.Context, namespace string, discover bool, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	var opts []rs.ResourceOption
+	profile := map[string]interface{}{
+		"id":   namespaceID(namespace),
+		"path": namespace,
+	}
+
+	namespaceTraitOptions := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+	opts = append(opts,
+		rs.WithAppTrait(namespaceTraitOptions...),
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: roleResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: policyResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: secretEngineResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: authMethodResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: groupResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: entityResourceType.Id},
+		),
+	)
+	if discover {
+		opts = append(opts, rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: namespaceResourceType.Id}))
+	}
+
+	resource, err := rs.NewResource(
+		namespaceName(namespace),
+		namespaceResourceType,
+		namespaceID(namespace),
 		opts...,
 	)
 	if err != nil {
@@ -223,6 +478,70 @@ func entityResource(ctx context.Context, entity *client.APIResource, parentResou
 	return resource, nil
 }
 
+// namespaceID returns the resource ID of a namespace path, ex. "bu1/team-a/" becomes "bu1/team-a".
+func namespaceID(namespace string) string {
+	if namespace == "" {
+		return rootNamespaceID
+	}
+
+	return strings.TrimSuffix(namespace, "/")
+}
+
+// namespacePath is the inverse of namespaceID.
+func namespacePath(id string) string {
+	if id == rootNamespaceID {
+		return ""
+	}
+
+	return client.NormalizeNamespace(id)
+}
+
+func namespaceName(namespace string) string {
+	if namespace == "" {
+		return rootNamespaceID
+	}
+
+	return path.Base(namespace)
+}
+
+// namespaceOf returns the namespace path of a resource emitted under a namespace.
+func namespaceOf(resource *v2.Resource) string {
+	parent := resource.GetParentResourceId()
+	if parent == nil || parent.ResourceType != namespaceResourceType.Id {
+		return ""
+	}
+
+	return namespacePath(parent.Resource)
+}
+
+// mountQualifiedID prefixes the name of an auth method object with its mount, ex. "userpass-contractors/alice",
+// so objects sharing a name on different mounts do not collide.
+func mountQualifiedID(mount, name string) string {
+	return mount + "/" + name
+}
+
+// splitMountQualifiedID is the inverse of mountQualifiedID. IDs without a mount belong to defaultMount.
+func splitMountQualifiedID(id, defaultMount string) (string, string) {
+	pos := strings.LastIndex(id, "/")
+	if pos == NF {
+		return defaultMount, id
+	}
+
+	return id[:pos], id[pos+1:]
+}
+
+// rateLimitAnnotations tells the runtime that Vault throttled the requests made since the previous page.
+func rateLimitAnnotations(c *client.HCPClient) annotations.Annotations {
+	rl := c.TakeRateLimit()
+	if rl == nil {
+		return nil
+	}
+
+	annos := annotations.Annotations{}
+	annos.WithRateLimiting(rl)
+	return annos
+}
+
 func removeTrailingSlash(strPath string) string {
 	regex := regexp.MustCompile(`/`)
 	return regex.ReplaceAllString(strPath, "")
@@ -27,6 +27,7 @@ var (
 func getClientForTesting(ctx context.Context, host string) (*client.HCPClient, error) {
 	hcpClient := client.NewClient()
 	hcpClient.WithBearerToken(vaultToken)
+	hcpClient.WithReadOnly(false)
 	err := hcpClient.WithAddress(host)
 	if err != nil {
 		return nil, err
@@ -37,6 +38,11 @@ func getClientForTesting(ctx context.Context, host string) (*client.HCPClient, e
 		return nil, err
 	}
 
+	err = hcpClient.EnableStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return hcpClient, nil
 }
 
@@ -120,7 +126,10 @@ func TestSecretsBuilderList(t *testing.T) {
 	}
 	var token = "{}"
 	for token != "" {
-		_, tk, _, err := s.List(ctxTest, &v2.ResourceId{}, &pagination.Token{
+		_, tk, _, err := s.List(ctxTest, &v2.ResourceId{
+			ResourceType: secretEngineResourceType.Id,
+			Resource:     "kv",
+		}, &pagination.Token{
 			Token: token,
 		})
 		require.Nil(t, err)
@@ -224,7 +233,7 @@ func getPolicyForTesting(ctxTest context.Context, id string, name string) (*v2.R
 	return policyResource(ctxTest, &client.APIResource{
 		ID:   id,
 		Name: name,
-	}, nil)
+	}, nil, nil)
 }
 
 func TestPolicyGrant(t *testing.T) {
@@ -289,6 +298,66 @@ func TestPolicyRevoke(t *testing.T) {
 	}
 }
 
+func TestPolicyGrantRevokeRole(t *testing.T) {
+	if vaultToken == "" && vaultHost == "" {
+		t.Skip()
+	}
+
+	cliTest, err := getClientForTesting(ctxTest, client.DefaultAddress)
+	require.Nil(t, err)
+
+	resource, err := getPolicyForTesting(ctxTest, "default", "default")
+	require.Nil(t, err)
+
+	role := &v2.Resource{
+		Id: &v2.ResourceId{
+			ResourceType: roleResourceType.Id,
+			Resource:     mountQualifiedID(client.DefaultAppRoleMount, strings.ReplaceAll(mockdata.NAMES[0], " ", "")),
+		},
+	}
+	r := &policyBuilder{
+		resourceType: policyResourceType,
+		client:       cliTest,
+	}
+	_, err = r.Grant(ctxTest, role, getEntitlementForTesting(resource, "role", assignedEntitlement))
+	require.Nil(t, err)
+
+	_, err = r.Revoke(ctxTest, grant.NewGrant(resource, assignedEntitlement, role.Id))
+	require.Nil(t, err)
+}
+
+func TestRoleGrantRevoke(t *testing.T) {
+	if vaultToken == "" && vaultHost == "" {
+		t.Skip()
+	}
+
+	cliTest, err := getClientForTesting(ctxTest, client.DefaultAddress)
+	require.Nil(t, err)
+
+	roleName := strings.ReplaceAll(mockdata.NAMES[0], " ", "")
+	resource, err := roleResource(ctxTest, &client.APIResource{
+		ID:   mountQualifiedID(client.DefaultAppRoleMount, roleName),
+		Name: roleName,
+	}, "", &client.RoleData{}, nil)
+	require.Nil(t, err)
+
+	user := &v2.Resource{
+		Id: &v2.ResourceId{
+			ResourceType: userResourceType.Id,
+			Resource:     mountQualifiedID(client.DefaultUserpassMount, "mitchellh"),
+		},
+	}
+	r := &roleBuilder{
+		resourceType: roleResourceType,
+		client:       cliTest,
+	}
+	_, err = r.Grant(ctxTest, user, getEntitlementForTesting(resource, "user", assignedEntitlement))
+	require.Nil(t, err)
+
+	_, err = r.Revoke(ctxTest, grant.NewGrant(resource, assignedEntitlement, user.Id))
+	require.Nil(t, err)
+}
+
 func TestAddUsers(t *testing.T) {
 	var count = 5
 	if vaultToken == "" && vaultHost == "" {
@@ -386,12 +455,24 @@ func TestPolicyGrants(t *testing.T) {
 	cliTest, err := getClientForTesting(ctxTest, client.DefaultAddress)
 	require.Nil(t, err)
 
-	d := &policyBuilder{
-		resourceType: policyResourceType,
-		client:       cliTest,
-	}
+	d := newPolicyBuilder(cliTest, newSyncCache())
 	_, _, _, err = d.Grants(ctxTest, &v2.Resource{
 		Id: &v2.ResourceId{ResourceType: policyResourceType.Id, Resource: "root"},
 	}, &pagination.Token{})
 	require.Nil(t, err)
 }
+
+func TestValidate(t *testing.T) {
+	if vaultToken == "" && vaultHost == "" {
+		t.Skip()
+	}
+
+	cliTest, err := getClientForTesting(ctxTest, client.DefaultAddress)
+	require.Nil(t, err)
+
+	c := &Connector{
+		client: cliTest,
+	}
+	_, err = c.Validate(ctxTest)
+	require.Nil(t, err)
+}
@@ -0,0 +1,81 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+type namespaceBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.HCPClient
+}
+
+func (n *namespaceBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return namespaceResourceType
+}
+
+// List returns the configured namespace at the top level, and the direct child namespaces of a namespace
+// when namespace discovery is enabled.
+func (n *namespaceBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var (
+		err        error
+		rv         []*v2.Resource
+		namespaces []string
+	)
+
+	bag, _, err := getToken(pToken, namespaceResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	switch {
+	case parentResourceID == nil:
+		namespaces = []string{n.client.RootNamespace()}
+	case n.client.DiscoversNamespaces():
+		namespaces, err = n.client.Namespace(namespacePath(parentResourceID.Resource)).GetNamespaces(ctx)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	err = bag.Next("")
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, namespace := range namespaces {
+		nr, err := namespaceResource(ctx, namespace, n.client.DiscoversNamespaces(), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, nr)
+	}
+
+	nextPageToken, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, rateLimitAnnotations(n.client), nil
+}
+
+// Entitlements always returns an empty slice for namespaces.
+func (n *namespaceBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for namespaces since they don't have any entitlements.
+func (n *namespaceBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newNamespaceBuilder(c *client.HCPClient) *namespaceBuilder {
+	return &namespaceBuilder{
+		resourceType: namespaceResourceType,
+		client:       c,
+	}
+}
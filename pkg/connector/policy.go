@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -13,13 +15,26 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type policyBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.HCPClient
+	cache        *syncCache
 }
 
+// Sources a user gets a policy from.
+const (
+	policySourceDirect  = "direct"
+	policySourceDefault = "default"
+	policySourceEntity  = "entity"
+	policySourceGroup   = "group"
+	defaultPolicy       = "default"
+)
+
 func (p *policyBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return policyResourceType
 }
@@ -29,6 +44,11 @@ func (p *policyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		err error
 		rv  []*v2.Resource
 	)
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	namespace := namespacePath(parentResourceID.Resource)
 	_, bag, err := unmarshalSkipToken(pToken)
 	if err != nil {
 		return nil, "", nil, err
@@ -40,7 +60,7 @@ func (p *policyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		})
 	}
 
-	policies, nextPageToken, err := p.client.ListAllPolicies(ctx)
+	policies, nextPageToken, err := p.client.Namespace(namespace).ListAllPolicies(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -51,11 +71,16 @@ func (p *policyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 	}
 
 	for _, policy := range policies.Data.Policies {
+		rules, err := p.cache.policyRules(ctx, p.client, namespace, policy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
 		ur, err := policyResource(ctx, &client.APIResource{
-			ID:        policy,
+			ID:        client.QualifyID(namespace, policy),
 			Name:      policy,
 			MountType: policies.MountType,
-		}, nil)
+		}, rules, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -67,32 +92,77 @@ func (p *policyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
+	return rv, nextPageToken, rateLimitAnnotations(p.client), nil
 }
 
-func (p *policyBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+// Entitlements returns the assignment of the policy and one entitlement per path the policy has rules for.
+func (p *policyBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
 	assigmentOptions := []ent.EntitlementOption{
-		ent.WithGrantableTo(userResourceType),
+		ent.WithGrantableTo(userResourceType, groupResourceType, entityResourceType, roleResourceType),
 		ent.WithDescription(fmt.Sprintf("Assigned to %s policy", resource.DisplayName)),
 		ent.WithDisplayName(fmt.Sprintf("%s policy %s", resource.DisplayName, assignedEntitlement)),
 	}
 	rv = append(rv, ent.NewAssignmentEntitlement(resource, assignedEntitlement, assigmentOptions...))
 
-	return rv, "", nil, nil
+	namespace := namespaceOf(resource)
+	rules, err := p.cache.policyRules(ctx, p.client, namespace, client.UnqualifyID(namespace, resource.Id.Resource))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, rule := range rules {
+		pathOptions := []ent.EntitlementOption{
+			ent.WithGrantableTo(policyResourceType),
+			ent.WithDescription(fmt.Sprintf("%s on %s", strings.Join(rule.Capabilities, ", "), rule.Path)),
+			ent.WithDisplayName(fmt.Sprintf("%s policy %s", resource.DisplayName, rule.Path)),
+		}
+		rv = append(rv, ent.NewPermissionEntitlement(resource, rule.Path, pathOptions...))
+	}
+
+	return rv, "", rateLimitAnnotations(p.client), nil
 }
 
+// Grants returns the principals the policy is attached to: userpass users and AppRole roles through their
+// token_policies, then identity groups and entities through their policies. The path entitlements are granted
+// to the policy itself and expanded to its holders.
 func (p *policyBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var (
 		err error
 		rv  []*v2.Grant
 	)
-	bag, _, err := getToken(pToken, policyResourceType)
+	bag := &pagination.Bag{}
+	err = bag.Unmarshal(pToken.Token)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	users, nextPageToken, err := p.client.ListAllUsers(ctx)
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: policyResourceType.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: entityResourceType.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: groupResourceType.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: roleResourceType.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: userResourceType.Id})
+	}
+
+	namespace := namespaceOf(resource)
+	policyName := client.UnqualifyID(namespace, resource.Id.Resource)
+	cli := p.client.Namespace(namespace)
+	nextPageToken := ""
+	switch bag.ResourceTypeID() {
+	case userResourceType.Id:
+		rv, nextPageToken, err = p.userGrants(ctx, cli, resource, namespace, policyName, bag.PageToken())
+	case roleResourceType.Id:
+		rv, nextPageToken, err = p.roleGrants(ctx, cli, resource, namespace, policyName, bag.PageToken())
+	case groupResourceType.Id:
+		rv, err = groupPolicyGrants(ctx, p.client, p.cache, resource, namespace, policyName)
+	case entityResourceType.Id:
+		rv, err = entityPolicyGrants(ctx, p.client, p.cache, resource, namespace, policyName)
+	case policyResourceType.Id:
+		rv, err = p.pathGrants(ctx, resource, namespace, policyName)
+	default:
+		return nil, "", nil, fmt.Errorf("hcp-connector: unexpected resource type %s in policy grants", bag.ResourceTypeID())
+	}
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -102,109 +172,398 @@ func (p *policyBuilder) Grants(ctx context.Context, resource *v2.Resource, pToke
 		return nil, "", nil, err
 	}
 
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, rateLimitAnnotations(p.client), nil
+}
+
+// pathGrants grants every path entitlement of the policy to the policy itself, the holders of its assignment
+// inherit them.
+func (p *policyBuilder) pathGrants(ctx context.Context, resource *v2.Resource, namespace, policyName string) ([]*v2.Grant, error) {
+	rules, err := p.cache.policyRules(ctx, p.client, namespace, policyName)
+	if err != nil {
+		return nil, err
+	}
+
+	expandable := &v2.GrantExpandable{
+		EntitlementIds: []string{ent.NewEntitlementID(resource, assignedEntitlement)},
+	}
+
+	rv := make([]*v2.Grant, 0, len(rules))
+	for _, rule := range rules {
+		rv = append(rv, grant.NewGrant(resource, rule.Path, resource.Id, grant.WithAnnotation(expandable)))
+	}
+
+	return rv, nil
+}
+
+// userGrants returns the users of one userpass mount having the policy in effect: through their token_policies,
+// as the default policy, or through their identity entity and the groups the entity belongs to, directly or nested.
+// Each grant carries the sources of the policy, grants without a direct source cannot be revoked from the user.
+func (p *policyBuilder) userGrants(ctx context.Context, cli *client.HCPClient, resource *v2.Resource, namespace, policyName, token string) ([]*v2.Grant, string, error) {
+	var rv []*v2.Grant
+	pageToken := 0
+	if token != "" {
+		var err error
+		pageToken, err = strconv.Atoi(token)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	mount, users, nextPageToken, err := cli.ListAllUsers(ctx, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
 	for _, user := range users.Data.Keys {
-		userInfo, err := p.client.GetUser(ctx, user)
+		userInfo, err := cli.GetUser(ctx, mount.Path, user)
 		if err != nil {
-			return nil, "", nil, err
+			return nil, "", err
 		}
 
-		for _, userPolicy := range userInfo.Data.TokenPolicies {
-			if userPolicy != resource.Id.Resource {
-				continue
-			}
+		var sources []string
+		if slices.Contains(userInfo.Data.TokenPolicies, policyName) {
+			sources = append(sources, policySourceDirect)
+		}
+
+		if policyName == defaultPolicy && !userInfo.Data.TokenNoDefaultPolicy {
+			sources = append(sources, policySourceDefault)
+		}
+
+		entitySources, groups, err := p.entityPolicySources(ctx, namespace, mount.Accessor, user, policyName)
+		if err != nil {
+			return nil, "", err
+		}
 
-			grant := grant.NewGrant(resource, assignedEntitlement, &v2.ResourceId{
-				ResourceType: userResourceType.Id,
-				Resource:     user,
-			})
-			rv = append(rv, grant)
+		sources = append(sources, entitySources...)
+		if len(sources) == 0 {
+			continue
 		}
+
+		grantOptions, err := policySourceAnnotations(sources, groups)
+		if err != nil {
+			return nil, "", err
+		}
+
+		rv = append(rv, grant.NewGrant(resource, assignedEntitlement, &v2.ResourceId{
+			ResourceType: userResourceType.Id,
+			Resource:     client.QualifyID(namespace, mountQualifiedID(mount.Path, user)),
+		}, grantOptions...))
 	}
 
-	nextPageToken, err = bag.Marshal()
+	return rv, nextPageToken, nil
+}
+
+// entityPolicySources returns whether the entity of an alias has the policy itself and the names of the groups,
+// direct or nested, giving it the policy.
+func (p *policyBuilder) entityPolicySources(ctx context.Context, namespace, mountAccessor, aliasName, policyName string) ([]string, []string, error) {
+	identity, err := p.cache.identity(ctx, p.client, namespace)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
-}
+	entity, ok := identity.entities[identity.aliases[mountQualifiedID(mountAccessor, aliasName)]]
+	if !ok {
+		return nil, nil, nil
+	}
 
-func (p *policyBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
-	l := ctxzap.Extract(ctx)
-	if principal.Id.ResourceType != userResourceType.Id {
-		l.Warn(
-			"hcp-connector: only users can be granted policy membership",
-			zap.String("principal_type", principal.Id.ResourceType),
-			zap.String("principal_id", principal.Id.Resource),
-		)
-		return nil, fmt.Errorf("hcp-connector: only users can be granted policy membership")
+	var (
+		sources []string
+		groups  []string
+	)
+	if slices.Contains(entity.Policies, policyName) {
+		sources = append(sources, policySourceEntity)
+	}
+
+	for _, groupId := range slices.Concat(entity.DirectGroupIDs, entity.InheritedGroupIDs) {
+		group, ok := identity.groups[groupId]
+		if ok && slices.Contains(group.Policies, policyName) && !slices.Contains(groups, group.Name) {
+			groups = append(groups, group.Name)
+		}
+	}
+
+	if len(groups) > 0 {
+		sources = append(sources, policySourceGroup)
 	}
 
-	policyId := entitlement.Resource.Id.Resource
-	userId := principal.Id.Resource
-	userInfo, err := p.client.GetUser(ctx, userId)
+	return sources, groups, nil
+}
+
+// policySourceAnnotations annotates a user policy grant with the sources of the policy. A policy the user
+// does not hold directly can only be revoked at its source, so the grant is marked immutable.
+func policySourceAnnotations(sources, groups []string) ([]grant.GrantOption, error) {
+	metadata, err := structpb.NewStruct(map[string]interface{}{
+		"sources": toInterfaces(sources),
+		"groups":  toInterfaces(groups),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var policies = []string{}
-	policies = append(policies, userInfo.Data.TokenPolicies...)
-	posPolicy := slices.IndexFunc(policies, func(c string) bool {
-		return c == policyId
-	})
-	if posPolicy == NF {
-		policies = append(policies, policyId)
+	if slices.Contains(sources, policySourceDirect) {
+		return []grant.GrantOption{grant.WithAnnotation(&v2.GrantMetadata{Metadata: metadata})}, nil
+	}
+
+	return []grant.GrantOption{grant.WithAnnotation(&v2.GrantImmutable{Metadata: metadata})}, nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	rv := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		rv = append(rv, value)
 	}
 
-	err = p.client.UpdateUserPolicy(ctx, policies, userId)
+	return rv
+}
+
+// roleGrants returns the roles of one approle mount having the policy in their token_policies.
+func (p *policyBuilder) roleGrants(ctx context.Context, cli *client.HCPClient, resource *v2.Resource, namespace, policyName, token string) ([]*v2.Grant, string, error) {
+	var rv []*v2.Grant
+	pageToken := 0
+	if token != "" {
+		var err error
+		pageToken, err = strconv.Atoi(token)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	mount, roles, nextPageToken, err := cli.ListAllRoles(ctx, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, role := range roles.Data.Keys {
+		roleInfo, err := cli.GetRole(ctx, mount.Path, role)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !slices.Contains(roleInfo.Data.TokenPolicies, policyName) {
+			continue
+		}
+
+		rv = append(rv, grant.NewGrant(resource, assignedEntitlement, &v2.ResourceId{
+			ResourceType: roleResourceType.Id,
+			Resource:     client.QualifyID(namespace, mountQualifiedID(mount.Accessor, role)),
+		}))
+	}
+
+	return rv, nextPageToken, nil
+}
+
+// groupPolicyGrants returns grants of the assigned entitlement of resource to the identity groups having the policy.
+// The members of an internal group inherit the grant.
+func groupPolicyGrants(ctx context.Context, c *client.HCPClient, cache *syncCache, resource *v2.Resource, namespace, policyName string) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+	identity, err := cache.identity(ctx, c, namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	for _, groupId := range identity.groupIds {
+		group := identity.groups[groupId]
+		if !slices.Contains(group.Policies, policyName) {
+			continue
+		}
+
+		groupID := &v2.ResourceId{
+			ResourceType: groupResourceType.Id,
+			Resource:     client.QualifyID(namespace, groupId),
+		}
+		rv = append(rv, grant.NewGrant(resource, assignedEntitlement, groupID, groupMemberExpansion(groupID, group)...))
+	}
+
+	return rv, nil
 }
 
-func (p *policyBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
-	l := ctxzap.Extract(ctx)
-	principal := grant.Principal
-	entitlement := grant.Entitlement
-	if principal.Id.ResourceType != userResourceType.Id {
-		l.Warn(
-			"hcp-connector: only users can have policy membership revoked",
-			zap.String("principal_id", principal.Id.String()),
-			zap.String("principal_type", principal.Id.ResourceType),
-		)
+// entityPolicyGrants returns grants of the assigned entitlement of resource to the identity entities having the policy.
+func entityPolicyGrants(ctx context.Context, c *client.HCPClient, cache *syncCache, resource *v2.Resource, namespace, policyName string) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+	identity, err := cache.identity(ctx, c, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entityId := range identity.entityIds {
+		if !slices.Contains(identity.entities[entityId].Policies, policyName) {
+			continue
+		}
 
-		return nil, fmt.Errorf("hcp-connector: only users can have policy membership revoked")
+		rv = append(rv, grant.NewGrant(resource, assignedEntitlement, &v2.ResourceId{
+			ResourceType: entityResourceType.Id,
+			Resource:     client.QualifyID(namespace, entityId),
+		}))
 	}
 
-	userId := principal.Id.Resource
-	policyId := entitlement.Resource.Id.Resource
-	userInfo, err := p.client.GetUser(ctx, userId)
+	return rv, nil
+}
+
+// Grant attaches the policy to a userpass user or an AppRole role through their token_policies,
+// or to an identity group or entity through their policies.
+func (p *policyBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	namespace, policyId, err := p.client.SplitNamespacedID(ctx, entitlement.Resource.Id.Resource)
 	if err != nil {
 		return nil, err
 	}
 
-	var policies = []string{}
-	policies = append(policies, userInfo.Data.TokenPolicies...)
-	posPolicy := slices.IndexFunc(policies, func(c string) bool {
-		return c == policyId
+	updated, err := updatePrincipalPolicies(ctx, p.client, namespace, principal.Id, func(policies []string) []string {
+		if slices.Contains(policies, policyId) {
+			return policies
+		}
+
+		return append(policies, policyId)
 	})
-	if posPolicy != NF {
-		policies = RemoveIndex(policies, posPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	return nil, nil
+}
+
+// Revoke detaches the policy from the principal of the grant.
+func (p *policyBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	namespace, policyId, err := p.client.SplitNamespacedID(ctx, grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
 	}
 
-	err = p.client.UpdateUserPolicy(ctx, policies, userId)
+	updated, err := updatePrincipalPolicies(ctx, p.client, namespace, grant.Principal.Id, func(policies []string) []string {
+		posPolicy := slices.Index(policies, policyId)
+		if posPolicy == NF {
+			return policies
+		}
+
+		return RemoveIndex(policies, posPolicy)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if !updated {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
 	return nil, nil
 }
 
-func newPolicyBuilder(c *client.HCPClient) *policyBuilder {
+// updatePrincipalPolicies reads the policies of a principal, applies update to them and writes them back
+// when they changed. It reports whether a write was made.
+func updatePrincipalPolicies(ctx context.Context, c *client.HCPClient, namespace string, principal *v2.ResourceId, update func([]string) []string) (bool, error) {
+	l := ctxzap.Extract(ctx)
+	// The policies are read around the response cache so a grant made earlier in the run is not undone.
+	principalNamespace, principalId, err := c.SplitNamespacedID(ctx, principal.Resource)
+	if err != nil {
+		return false, err
+	}
+
+	if principalNamespace != namespace {
+		return false, status.Errorf(codes.InvalidArgument,
+			"hcp-connector: %s %s is not in the namespace of the policy", principal.ResourceType, principal.Resource)
+	}
+
+	cli := c.Namespace(namespace).Uncached()
+	switch principal.ResourceType {
+	case userResourceType.Id:
+		mount, userId := splitMountQualifiedID(principalId, client.DefaultUserpassMount)
+		userInfo, err := cli.GetUser(ctx, mount, userId)
+		if err != nil {
+			return false, err
+		}
+
+		policies, changed := applyPolicyUpdate(userInfo.Data.TokenPolicies, update)
+		if !changed {
+			return false, nil
+		}
+
+		return true, cli.UpdateUserPolicy(ctx, policies, mount, userId)
+	case roleResourceType.Id:
+		accessor, roleName := splitMountQualifiedID(principalId, client.DefaultAppRoleMount)
+		mount, err := findAuthMount(ctx, cli, client.ApproleType, accessor)
+		if err != nil {
+			return false, err
+		}
+
+		roleInfo, err := cli.GetRole(ctx, mount.Path, roleName)
+		if err != nil {
+			return false, err
+		}
+
+		policies, changed := applyPolicyUpdate(roleInfo.Data.TokenPolicies, update)
+		if !changed {
+			return false, nil
+		}
+
+		return true, cli.UpdateRolePolicies(ctx, policies, mount.Path, roleName)
+	case groupResourceType.Id:
+		groupInfo, err := cli.GetGroup(ctx, principalId)
+		if err != nil {
+			return false, err
+		}
+
+		policies, changed := applyPolicyUpdate(groupInfo.Data.Policies, update)
+		if !changed {
+			return false, nil
+		}
+
+		return true, cli.UpdateGroupPolicies(ctx, policies, principalId)
+	case entityResourceType.Id:
+		entityInfo, err := cli.GetEntity(ctx, principalId)
+		if err != nil {
+			return false, err
+		}
+
+		policies, changed := applyPolicyUpdate(entityInfo.Data.Policies, update)
+		if !changed {
+			return false, nil
+		}
+
+		return true, cli.UpdateEntityPolicies(ctx, policies, principalId)
+	}
+
+	l.Warn(
+		"hcp-connector: principal cannot hold policies",
+		zap.String("principal_type", principal.ResourceType),
+		zap.String("principal_id", principal.Resource),
+	)
+
+	return false, fmt.Errorf("hcp-connector: %s principals cannot hold policies", principal.ResourceType)
+}
+
+// applyPolicyUpdate applies update to a copy of policies and reports whether it changed them.
+func applyPolicyUpdate(policies []string, update func([]string) []string) ([]string, bool) {
+	updated := update(slices.Clone(policies))
+	return updated, !slices.Equal(policies, updated)
+}
+
+// findAuthMount returns the auth method of the given type mounted at, or with the accessor, mount.
+func findAuthMount(ctx context.Context, c *client.HCPClient, mountType, mount string) (client.AuthMount, error) {
+	mounts, err := c.ListAuthMounts(ctx, mountType)
+	if err != nil {
+		return client.AuthMount{}, err
+	}
+
+	for _, m := range mounts {
+		if m.Accessor == mount || m.Path == mount {
+			return m, nil
+		}
+	}
+
+	return client.AuthMount{}, fmt.Errorf("hcp-connector: no %s auth method mounted at %s", mountType, mount)
+}
+
+func newPolicyBuilder(c *client.HCPClient, cache *syncCache) *policyBuilder {
 	return &policyBuilder{
 		resourceType: policyResourceType,
 		client:       c,
+		cache:        cache,
 	}
 }
@@ -0,0 +1,108 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPolicyGrantOtherNamespace(t *testing.T) {
+	vault := newFakeVault()
+	vault.namespaces = []string{"bu1/"}
+	vault.policies["app"] = ""
+
+	c := newTestConnector(t, vault, func(cli *client.HCPClient) {
+		cli.WithNamespaceDiscovery(true)
+	})
+	p := newPolicyBuilder(c.client, c.cache)
+	policy := &v2.Resource{Id: &v2.ResourceId{ResourceType: policyResourceType.Id, Resource: "app"}}
+	_, err := p.Grant(ctxTest, &v2.Resource{
+		Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: client.QualifyID("bu1/", "userpass/alice")},
+	}, &v2.Entitlement{Resource: policy})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPolicyGrantsToGroupsAndEntities(t *testing.T) {
+	vault := newFakeVault()
+	vault.policies["ops"] = ""
+	vault.groups["g-1"] = &client.GroupData{ID: "g-1", Name: "admins", Type: client.GroupTypeInternal, Policies: []string{"ops"}}
+	vault.groups["g-2"] = &client.GroupData{ID: "g-2", Name: "devs", Type: client.GroupTypeInternal, Policies: []string{"default"}}
+	vault.entities["e-1"] = &client.EntityData{ID: "e-1", Policies: []string{"ops"}}
+	vault.entities["e-2"] = &client.EntityData{ID: "e-2"}
+	vault.roles["approle"]["ci"] = &client.RoleData{TokenPolicies: []string{"ops"}}
+
+	c := newTestConnector(t, vault)
+	policy := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: policyResourceType.Id, Resource: "ops"},
+		ParentResourceId: rootNamespaceResourceID,
+	}
+
+	holders := map[string][]string{}
+	for _, g := range allGrants(t, newPolicyBuilder(c.client, c.cache), policy) {
+		require.Equal(t, "policy:ops:assigned", g.Entitlement.Id)
+		holders[g.Principal.Id.ResourceType] = append(holders[g.Principal.Id.ResourceType], g.Principal.Id.Resource)
+	}
+
+	require.Equal(t, map[string][]string{
+		groupResourceType.Id:  {"g-1"},
+		entityResourceType.Id: {"e-1"},
+		roleResourceType.Id:   {mountQualifiedID("auth_approle_1", "ci")},
+	}, holders)
+}
+
+func TestPolicyAssignmentGrantableTo(t *testing.T) {
+	vault := newFakeVault()
+	vault.policies["ops"] = ""
+
+	c := newTestConnector(t, vault)
+	entitlements, _, _, err := newPolicyBuilder(c.client, c.cache).Entitlements(ctxTest, &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: policyResourceType.Id, Resource: "ops"},
+		ParentResourceId: rootNamespaceResourceID,
+	}, &pagination.Token{})
+	require.Nil(t, err)
+	require.Len(t, entitlements, 1)
+	require.ElementsMatch(t, []*v2.ResourceType{userResourceType, roleResourceType, groupResourceType, entityResourceType},
+		entitlements[0].GrantableTo)
+}
+
+func TestPolicyGrantRevokeGroupAndEntity(t *testing.T) {
+	vault := newFakeVault()
+	vault.policies["ops"] = ""
+	vault.groups["g-1"] = &client.GroupData{ID: "g-1", Name: "admins", Type: client.GroupTypeInternal, Policies: []string{"default"}}
+	vault.entities["e-1"] = &client.EntityData{ID: "e-1"}
+
+	c := newTestConnector(t, vault)
+	p := newPolicyBuilder(c.client, c.cache)
+	assigned := &v2.Entitlement{
+		Resource: &v2.Resource{Id: &v2.ResourceId{ResourceType: policyResourceType.Id, Resource: "ops"}},
+	}
+	group := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "g-1"}}
+	entity := &v2.Resource{Id: &v2.ResourceId{ResourceType: entityResourceType.Id, Resource: "e-1"}}
+
+	for _, principal := range []*v2.Resource{group, entity} {
+		annos, err := p.Grant(ctxTest, principal, assigned)
+		require.Nil(t, err)
+		require.Empty(t, annos)
+
+		annos, err = p.Grant(ctxTest, principal, assigned)
+		require.Nil(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	}
+
+	require.Equal(t, []string{"default", "ops"}, vault.groups["g-1"].Policies)
+	require.Equal(t, []string{"ops"}, vault.entities["e-1"].Policies)
+
+	for _, principal := range []*v2.Resource{group, entity} {
+		annos, err := p.Revoke(ctxTest, &v2.Grant{Entitlement: assigned, Principal: principal})
+		require.Nil(t, err)
+		require.Empty(t, annos)
+	}
+
+	require.Equal(t, []string{"default"}, vault.groups["g-1"].Policies)
+	require.Empty(t, vault.entities["e-1"].Policies)
+}
@@ -8,7 +8,16 @@ import (
 // https://developer.hashicorp.com/boundary/docs/api-clients/api/pagination
 const ITEMSPERPAGE = 1000
 
+// rootNamespaceID is the resource ID of the Vault root namespace, a name Vault reserves.
+const rootNamespaceID = "root"
+
 var (
+	namespaceResourceType = &v2.ResourceType{
+		Id:          "namespace",
+		DisplayName: "Namespace",
+		Description: "Namespace of Hashicorp Vault",
+	}
+
 	userResourceType = &v2.ResourceType{
 		Id:          "user",
 		DisplayName: "User",
@@ -28,6 +37,18 @@ var (
 		Description: "Policy of Hashicorp Vault",
 	}
 
+	secretEngineResourceType = &v2.ResourceType{
+		Id:          "secret_engine",
+		DisplayName: "Secret Engine",
+		Description: "KV secrets engine of Hashicorp Vault",
+	}
+
+	secretFolderResourceType = &v2.ResourceType{
+		Id:          "secret_folder",
+		DisplayName: "Secret Folder",
+		Description: "Folder of a KV secrets engine of Hashicorp Vault",
+	}
+
 	secretResourceType = &v2.ResourceType{
 		Id:          "secret",
 		DisplayName: "Secret",
@@ -51,5 +72,6 @@ var (
 		Id:          "entity",
 		DisplayName: "Entity",
 		Description: "Entity of Hashicorp Vault",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
 	}
 )
@@ -3,17 +3,23 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 
 	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type roleBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.HCPClient
+	cache        *syncCache
 }
 
 const (
@@ -30,12 +36,19 @@ func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		err error
 		rv  []*v2.Resource
 	)
-	bag, _, err := getToken(pToken, roleResourceType)
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	namespace := namespacePath(parentResourceID.Resource)
+	cli := r.client.Namespace(namespace)
+	bag, pageToken, err := getToken(pToken, roleResourceType)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	roles, nextPageToken, err := r.client.ListAllRoles(ctx)
+	// Every page holds the roles of one approle mount.
+	mount, roles, nextPageToken, err := cli.ListAllRoles(ctx, pageToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -45,12 +58,24 @@ func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		return nil, "", nil, err
 	}
 
-	for _, user := range roles.Data.Keys {
+	for _, role := range roles.Data.Keys {
+		roleID, err := cli.GetRoleID(ctx, mount.Path, role)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		roleInfo, err := cli.GetRole(ctx, mount.Path, role)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
 		ur, err := roleResource(ctx, &client.APIResource{
-			ID:        user,
-			Name:      user,
-			MountType: roles.MountType,
-		}, nil)
+			ID:            client.QualifyID(namespace, mountQualifiedID(mount.Accessor, role)),
+			Name:          role,
+			MountType:     mount.Type,
+			MountPath:     mount.Path,
+			MountAccessor: mount.Accessor,
+		}, roleID, &roleInfo.Data, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -62,14 +87,15 @@ func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
+	return rv, nextPageToken, rateLimitAnnotations(r.client), nil
 }
 
+// Entitlements returns the assignment of the role, which lets its holders fetch secret IDs for the role.
 func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
 	assigmentOptions := []ent.EntitlementOption{
-		ent.WithGrantableTo(userResourceType),
-		ent.WithDescription(fmt.Sprintf("Assigned to %s role", resource.DisplayName)),
+		ent.WithGrantableTo(userResourceType, entityResourceType, groupResourceType),
+		ent.WithDescription(fmt.Sprintf("Can fetch secret IDs of %s role", resource.DisplayName)),
 		ent.WithDisplayName(fmt.Sprintf("%s role %s", resource.DisplayName, assignedEntitlement)),
 	}
 	rv = append(rv, ent.NewAssignmentEntitlement(resource, assignedEntitlement, assigmentOptions...))
@@ -77,21 +103,265 @@ func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *
 	return rv, "", nil, nil
 }
 
+// Grants returns the userpass users whose entity holds the secret ID policy of the role, then the identity
+// entities and the groups holding it.
 func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	var (
+		err error
+		rv  []*v2.Grant
+	)
+	bag := &pagination.Bag{}
+	err = bag.Unmarshal(pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: groupResourceType.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: entityResourceType.Id})
+		bag.Push(pagination.PageState{ResourceTypeID: userResourceType.Id})
+	}
+
+	namespace := namespaceOf(resource)
+	cli := r.client.Namespace(namespace)
+	mount, roleName, err := r.roleMount(ctx, cli, client.UnqualifyID(namespace, resource.Id.Resource))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	policyName := secretIDPolicyName(mount, roleName)
+	switch bag.ResourceTypeID() {
+	case userResourceType.Id:
+		rv, err = userEntityPolicyGrants(ctx, r.client, r.cache, resource, namespace, policyName)
+	case entityResourceType.Id:
+		rv, err = entityPolicyGrants(ctx, r.client, r.cache, resource, namespace, policyName)
+	case groupResourceType.Id:
+		rv, err = groupPolicyGrants(ctx, r.client, r.cache, resource, namespace, policyName)
+	default:
+		return nil, "", nil, fmt.Errorf("hcp-connector: unexpected resource type %s in role grants", bag.ResourceTypeID())
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next("")
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	nextPageToken, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, rateLimitAnnotations(r.client), nil
 }
 
+// userEntityPolicyGrants returns grants of the assigned entitlement of resource to the userpass users whose alias
+// belongs to an identity entity having the policy, which is where Grant attaches the policy for a user.
+func userEntityPolicyGrants(ctx context.Context, c *client.HCPClient, cache *syncCache, resource *v2.Resource, namespace, policyName string) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+	mounts, err := c.Namespace(namespace).ListAuthMounts(ctx, client.UserpassType)
+	if err != nil {
+		return nil, err
+	}
+
+	mountPaths := make(map[string]string, len(mounts))
+	for _, mount := range mounts {
+		mountPaths[mount.Accessor] = mount.Path
+	}
+
+	identity, err := cache.identity(ctx, c, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entityId := range identity.entityIds {
+		entity := identity.entities[entityId]
+		if !slices.Contains(entity.Policies, policyName) {
+			continue
+		}
+
+		for _, alias := range entity.Aliases {
+			mountPath, ok := mountPaths[alias.MountAccessor]
+			if !ok {
+				continue
+			}
+
+			rv = append(rv, grant.NewGrant(resource, assignedEntitlement, &v2.ResourceId{
+				ResourceType: userResourceType.Id,
+				Resource:     client.QualifyID(namespace, mountQualifiedID(mountPath, alias.Name)),
+			}))
+		}
+	}
+
+	return rv, nil
+}
+
+// Grant writes the secret ID policy of the role and attaches it to the entity of the principal, or to the group
+// principal. A userpass user gets an entity on its first login only, so a user that never logged in cannot be
+// granted a role.
 func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	namespace, roleId, err := r.client.SplitNamespacedID(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	cli := r.client.Namespace(namespace)
+	mount, roleName, err := r.roleMount(ctx, cli, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	holder, err := r.policyHolder(ctx, cli, namespace, principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if holder == nil {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"hcp-connector: user %s has no identity entity yet, it must log in once before being granted the role", principal.Id.Resource)
+	}
+
+	policyName := secretIDPolicyName(mount, roleName)
+	err = cli.WriteACLPolicy(ctx, policyName, secretIDPolicy(mount, roleName))
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := updatePrincipalPolicies(ctx, r.client, namespace, holder, func(policies []string) []string {
+		if slices.Contains(policies, policyName) {
+			return policies
+		}
+
+		return append(policies, policyName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
 	return nil, nil
 }
 
+// Revoke detaches the secret ID policy of the role from the entity or group of the grant principal.
+// The policy itself is kept since other principals may hold it.
 func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	namespace, roleId, err := r.client.SplitNamespacedID(ctx, grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	cli := r.client.Namespace(namespace)
+	mount, roleName, err := r.roleMount(ctx, cli, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	holder, err := r.policyHolder(ctx, cli, namespace, grant.Principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if holder == nil {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	policyName := secretIDPolicyName(mount, roleName)
+	updated, err := updatePrincipalPolicies(ctx, r.client, namespace, holder, func(policies []string) []string {
+		posPolicy := slices.Index(policies, policyName)
+		if posPolicy == NF {
+			return policies
+		}
+
+		return RemoveIndex(policies, posPolicy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
 	return nil, nil
 }
 
-func newRoleBuilder(c *client.HCPClient) *roleBuilder {
+// roleMount splits a role ID into the approle mount it belongs to and the role name.
+func (r *roleBuilder) roleMount(ctx context.Context, cli *client.HCPClient, roleId string) (client.AuthMount, string, error) {
+	accessor, roleName := splitMountQualifiedID(roleId, client.DefaultAppRoleMount)
+	mount, err := findAuthMount(ctx, cli, client.ApproleType, accessor)
+	if err != nil {
+		return client.AuthMount{}, "", err
+	}
+
+	return mount, roleName, nil
+}
+
+// policyHolder returns the principal holding the secret ID policy of a role for the given principal:
+// groups and entities hold it themselves, userpass users through the entity of their alias. A user without
+// an entity yields nil.
+func (r *roleBuilder) policyHolder(ctx context.Context, cli *client.HCPClient, namespace string, principal *v2.ResourceId) (*v2.ResourceId, error) {
+	principalNamespace, principalId, err := r.client.SplitNamespacedID(ctx, principal.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if principalNamespace != namespace {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"hcp-connector: %s %s is not in the namespace of the role", principal.ResourceType, principal.Resource)
+	}
+
+	switch principal.ResourceType {
+	case entityResourceType.Id, groupResourceType.Id:
+		return principal, nil
+	case userResourceType.Id:
+		mountPath, userName := splitMountQualifiedID(principalId, client.DefaultUserpassMount)
+		mount, err := findAuthMount(ctx, cli, client.UserpassType, mountPath)
+		if err != nil {
+			return nil, err
+		}
+
+		entity, err := cli.LookupEntityByAlias(ctx, userName, mount.Accessor)
+		if err != nil {
+			return nil, err
+		}
+
+		if entity == nil {
+			return nil, nil
+		}
+
+		return &v2.ResourceId{
+			ResourceType: entityResourceType.Id,
+			Resource:     client.QualifyID(namespace, entity.Data.ID),
+		}, nil
+	}
+
+	return nil, status.Errorf(codes.Unimplemented,
+		"hcp-connector: roles cannot be granted to %s principals", principal.ResourceType)
+}
+
+// secretIDPolicyName names the policy allowing to fetch secret IDs for a role, ex. "baton-secret-id-auth_approle_1a2b3c4d-ci".
+// The name follows the mount accessor, which a remount keeps, while the rules name the mount path: after the mount
+// is moved the policy still allows the old path until the role is granted again, which writes the policy anew.
+func secretIDPolicyName(mount client.AuthMount, roleName string) string {
+	return fmt.Sprintf("baton-secret-id-%s-%s", mount.Accessor, roleName)
+}
+
+// secretIDPolicy returns the rules of the secret ID policy of a role.
+// https://developer.hashicorp.com/vault/api-docs/auth/approle#generate-new-secret-id
+func secretIDPolicy(mount client.AuthMount, roleName string) string {
+	return fmt.Sprintf("path %s {\n  capabilities = [\"create\", \"update\"]\n}\n",
+		strconv.Quote(fmt.Sprintf("auth/%s/role/%s/secret-id", mount.Path, roleName)))
+}
+
+func newRoleBuilder(c *client.HCPClient, cache *syncCache) *roleBuilder {
 	return &roleBuilder{
 		resourceType: roleResourceType,
 		client:       c,
+		cache:        cache,
 	}
 }
@@ -0,0 +1,107 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// allGrants returns every page of grants of a resource.
+func allGrants(t *testing.T, b interface {
+	Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error)
+}, resource *v2.Resource) []*v2.Grant {
+	var rv []*v2.Grant
+	token := ""
+	for {
+		grants, next, _, err := b.Grants(ctxTest, resource, &pagination.Token{Token: token})
+		require.Nil(t, err)
+		rv = append(rv, grants...)
+		if next == "" {
+			return rv
+		}
+		token = next
+	}
+}
+
+func TestRoleGrantToUser(t *testing.T) {
+	vault := newFakeVault()
+	vault.users["userpass"]["alice"] = &client.UserData{}
+	vault.roles["approle"]["app"] = &client.RoleData{}
+
+	c := newTestConnector(t, vault)
+	r := newRoleBuilder(c.client, c.cache)
+	role := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: mountQualifiedID("auth_approle_1", "app")},
+		ParentResourceId: &v2.ResourceId{ResourceType: namespaceResourceType.Id, Resource: rootNamespaceID},
+	}
+	alice := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "userpass/alice"}}
+
+	// A user that never logged in has no entity to hold the policy, none is created for it.
+	_, err := r.Grant(ctxTest, alice, &v2.Entitlement{Resource: role})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Empty(t, vault.entities)
+	require.NotContains(t, vault.policies, "baton-secret-id-auth_approle_1-app")
+
+	vault.entities["e-alice"] = &client.EntityData{
+		ID:      "e-alice",
+		Aliases: []client.AliasData{{Name: "alice", MountAccessor: "auth_userpass_1"}},
+	}
+	_, err = r.Grant(ctxTest, alice, &v2.Entitlement{Resource: role})
+	require.Nil(t, err)
+	require.Equal(t, []string{"baton-secret-id-auth_approle_1-app"}, vault.entities["e-alice"].Policies)
+
+	principals := map[string]string{}
+	for _, g := range allGrants(t, r, role) {
+		principals[g.Principal.Id.Resource] = g.Principal.Id.ResourceType
+	}
+
+	// The user shows up as holding the role, along with its entity.
+	require.Equal(t, map[string]string{
+		"userpass/alice": userResourceType.Id,
+		"e-alice":        entityResourceType.Id,
+	}, principals)
+}
+
+func TestRolesListEveryAppRoleMount(t *testing.T) {
+	vault := newFakeVault()
+	vault.auth["approle-ci"] = client.AuthMount{Type: client.ApproleType, Accessor: "auth_approle_2"}
+	vault.roles["approle"]["app"] = &client.RoleData{
+		TokenPolicies:      []string{"app", "default"},
+		TokenTTL:           3600,
+		TokenBoundCidrs:    []string{"10.0.0.0/8"},
+		SecretIDBoundCidrs: []string{"10.1.0.0/16"},
+		BindSecretID:       true,
+	}
+	vault.roleIDs["approle"]["app"] = "role-id-app"
+	vault.roles["approle-ci"] = map[string]*client.RoleData{"app": {}}
+	vault.roleIDs["approle-ci"] = map[string]string{"app": "role-id-ci"}
+
+	c := newTestConnector(t, vault)
+	roles := map[string]map[string]interface{}{}
+	for _, role := range allResources(t, newRoleBuilder(c.client, c.cache), rootNamespaceResourceID) {
+		trait, err := rs.GetRoleTrait(role)
+		require.Nil(t, err)
+		roles[role.Id.Resource] = trait.Profile.AsMap()
+	}
+
+	// Roles of the same name on two mounts are keyed by the mount accessor.
+	require.Len(t, roles, 2)
+	require.Equal(t, "role-id-ci", roles[mountQualifiedID("auth_approle_2", "app")]["role_id"])
+
+	app := roles[mountQualifiedID("auth_approle_1", "app")]
+	require.Equal(t, "role-id-app", app["role_id"])
+	require.Equal(t, "approle", app["mount_path"])
+	require.Equal(t, "app,default", app["token_policies"])
+	require.EqualValues(t, 3600, app["token_ttl"])
+	require.Equal(t, "10.0.0.0/8", app["token_bound_cidrs"])
+	require.Equal(t, "10.1.0.0/16", app["secret_id_bound_cidrs"])
+	require.Equal(t, true, app["bind_secret_id"])
+}
@@ -2,49 +2,75 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 )
 
+// secretCapabilities are the capabilities synced as entitlements of secrets and secret folders.
+var secretCapabilities = []string{"read", "create", "update", "delete", "list", "sudo"}
+
+// secretsPageSize is how many secrets, or secret folders, of a folder are listed per page.
+const secretsPageSize = 100
+
 type secretBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.HCPClient
+	cache        *syncCache
 }
 
 func (s *secretBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return secretResourceType
 }
 
+// List returns the secrets directly under a secret engine or a secret folder, secretsPageSize at a time.
 func (s *secretBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	var (
-		err error
-		rv  []*v2.Resource
-	)
+	var rv []*v2.Resource
+	if !isSecretContainer(parentResourceID) {
+		return nil, "", nil, nil
+	}
 
-	bag, _, err := getToken(pToken, secretResourceType)
+	bag, pageToken, err := getToken(pToken, secretResourceType)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	secrets, nextPageToken, err := s.client.ListAllSecrets(ctx, bag.Current().Token)
+	folder, err := listKVFolder(ctx, s.client, parentResourceID.Resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	keys, nextPageToken := folder.page(pageToken, false)
 	err = bag.Next(nextPageToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for _, secret := range secrets.Data.Keys {
+	for _, key := range keys {
+		// Only the metadata of kv v2 secrets is read, their values never are. It is read for the secrets of the
+		// page only, so a large folder costs one bounded batch of reads per page.
+		var metadata *client.SecretMetadata
+		if folder.mount.KVVersion() == 2 {
+			res, err := folder.cli.GetSecretMetadata(ctx, folder.mount, folder.path+key)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			metadata = &res.Data
+		}
+
 		ur, err := secretResource(ctx, &client.APIResource{
-			ID:        secret,
-			Name:      secret,
-			MountType: secrets.MountType,
-		}, nil)
+			ID:        client.QualifyID(folder.namespace, folder.childPath(key)),
+			Name:      folder.childPath(key),
+			MountType: folder.mount.Type,
+			MountPath: folder.mount.Path,
+		}, metadata, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -56,20 +82,203 @@ func (s *secretBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
+	return rv, nextPageToken, rateLimitAnnotations(s.client), nil
 }
 
 func (s *secretBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	return secretCapabilityEntitlements(resource), "", nil, nil
 }
 
 func (s *secretBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	rv, err := secretCapabilityGrants(ctx, s.client, s.cache, resource, false)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, "", rateLimitAnnotations(s.client), nil
+}
+
+// secretCapabilityEntitlements returns one entitlement per capability a policy can give on a secret or a folder.
+func secretCapabilityEntitlements(resource *v2.Resource) []*v2.Entitlement {
+	var rv []*v2.Entitlement
+	for _, capability := range secretCapabilities {
+		options := []ent.EntitlementOption{
+			ent.WithGrantableTo(policyResourceType),
+			ent.WithDescription(fmt.Sprintf("Policies allowing %s on %s", capability, resource.DisplayName)),
+			ent.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, capability)),
+		}
+		rv = append(rv, ent.NewPermissionEntitlement(resource, capability, options...))
+	}
+
+	return rv
+}
+
+// secretCapabilityGrants grants every capability on a secret or a folder to the policies of its namespace
+// allowing it, the rule of the policy matching the path being picked by Vault's priority matching. The grants
+// are expanded to the holders of the policy assignment.
+func secretCapabilityGrants(ctx context.Context, c *client.HCPClient, cache *syncCache, resource *v2.Resource, folder bool) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+	namespace, secretPath, err := c.SplitNamespacedID(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts, err := c.Namespace(namespace).ListKVMounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if folder {
+		secretPath += "/"
+	}
+
+	mount, path, ok := findKVMount(mounts, secretPath)
+	if !ok {
+		return nil, fmt.Errorf("hcp-connector: kv mount of %s not found", resource.Id.Resource)
+	}
+
+	policies, err := cache.namespacePolicies(ctx, c, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		rules, err := cache.policyRules(ctx, c, namespace, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		policyID := &v2.ResourceId{ResourceType: policyResourceType.Id, Resource: client.QualifyID(namespace, policy)}
+		expandable := &v2.GrantExpandable{
+			EntitlementIds: []string{ent.NewEntitlementID(&v2.Resource{Id: policyID}, assignedEntitlement)},
+		}
+		for _, capability := range secretCapabilities {
+			// The root policy allows everything.
+			if policy != client.RootPolicy {
+				rule := client.MatchACLRule(rules, capabilityPath(mount, path, capability))
+				if rule == nil || !client.HasCapability(rule.Capabilities, capability) {
+					continue
+				}
+			}
+
+			rv = append(rv, grant.NewGrant(resource, capability, policyID, grant.WithAnnotation(expandable)))
+		}
+	}
+
+	return rv, nil
+}
+
+// capabilityPath returns the API path Vault checks a capability against, path being relative to the mount.
+// KV v2 serves secrets under data/ and lists them under metadata/.
+func capabilityPath(mount client.SecretsMount, path, capability string) string {
+	if mount.KVVersion() == 1 {
+		return mount.Path + "/" + path
+	}
+
+	if capability == "list" {
+		return mount.Path + "/metadata/" + path
+	}
+
+	return mount.Path + "/data/" + path
+}
+
+// kvFolder is the content of the top level or of a folder of a kv mount.
+type kvFolder struct {
+	namespace string
+	cli       *client.HCPClient
+	mount     client.SecretsMount
+	// path is the folder inside the mount with a trailing slash, "" for the top level.
+	path string
+	keys []string
+}
+
+// page returns the secrets, or the sub folders, of the folder starting at offset, at most secretsPageSize of
+// them, and the offset of the next page, "" after the last one. Vault lists keys in a stable sorted order.
+func (f *kvFolder) page(offset int, folders bool) ([]string, string) {
+	var keys []string
+	for _, key := range f.keys {
+		if strings.HasSuffix(key, "/") == folders {
+			keys = append(keys, key)
+		}
+	}
+
+	if offset >= len(keys) {
+		return nil, ""
+	}
+
+	end := min(offset+secretsPageSize, len(keys))
+	if end == len(keys) {
+		return keys[offset:end], ""
+	}
+
+	return keys[offset:end], strconv.Itoa(end)
+}
+
+// childPath returns the path of a key of the folder, mount included, ex. "kv/app/db".
+func (f *kvFolder) childPath(key string) string {
+	return f.mount.Path + "/" + f.path + strings.TrimSuffix(key, "/")
+}
+
+func isSecretContainer(parentResourceID *v2.ResourceId) bool {
+	if parentResourceID == nil {
+		return false
+	}
+
+	return parentResourceID.ResourceType == secretEngineResourceType.Id ||
+		parentResourceID.ResourceType == secretFolderResourceType.Id
+}
+
+// listKVFolder lists the keys of the secret engine or the secret folder with the given resource ID.
+func listKVFolder(ctx context.Context, c *client.HCPClient, resourceID string) (*kvFolder, error) {
+	namespace, folderPath, err := c.SplitNamespacedID(ctx, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	cli := c.Namespace(namespace)
+	mounts, err := cli.ListKVMounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mount, path, ok := findKVMount(mounts, folderPath+"/")
+	if !ok {
+		return nil, fmt.Errorf("hcp-connector: kv mount of %s not found", resourceID)
+	}
+
+	secrets, err := cli.GetSecrets(ctx, mount, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kvFolder{
+		namespace: namespace,
+		cli:       cli,
+		mount:     mount,
+		path:      path,
+		keys:      secrets.Data.Keys,
+	}, nil
+}
+
+// findKVMount returns the kv mount a path such as "kv/app/" belongs to and the folder inside that mount.
+func findKVMount(mounts []client.SecretsMount, secretPath string) (client.SecretsMount, string, bool) {
+	var (
+		found client.SecretsMount
+		ok    bool
+	)
+	for _, mount := range mounts {
+		if strings.HasPrefix(secretPath, mount.Path+"/") && len(mount.Path) > len(found.Path) {
+			found, ok = mount, true
+		}
+	}
+
+	return found, strings.TrimPrefix(secretPath, found.Path+"/"), ok
 }
 
-func newSecretBuilder(c *client.HCPClient) *secretBuilder {
+func newSecretBuilder(c *client.HCPClient, cache *syncCache) *secretBuilder {
 	return &secretBuilder{
 		resourceType: secretResourceType,
 		client:       c,
+		cache:        cache,
 	}
 }
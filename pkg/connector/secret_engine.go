@@ -0,0 +1,64 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+type secretEngineBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.HCPClient
+}
+
+func (s *secretEngineBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return secretEngineResourceType
+}
+
+// List returns the kv secrets engines of a namespace. Their folders and secrets are listed under them.
+func (s *secretEngineBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var (
+		err error
+		rv  []*v2.Resource
+	)
+
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	namespace := namespacePath(parentResourceID.Resource)
+	mounts, err := s.client.Namespace(namespace).ListKVMounts(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, mount := range mounts {
+		er, err := secretEngineResource(ctx, namespace, mount, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, er)
+	}
+
+	return rv, "", rateLimitAnnotations(s.client), nil
+}
+
+// Entitlements always returns an empty slice for secret engines.
+func (s *secretEngineBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for secret engines.
+func (s *secretEngineBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newSecretEngineBuilder(c *client.HCPClient) *secretEngineBuilder {
+	return &secretEngineBuilder{
+		resourceType: secretEngineResourceType,
+		client:       c,
+	}
+}
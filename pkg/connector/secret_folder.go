@@ -0,0 +1,85 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+type secretFolderBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.HCPClient
+	cache        *syncCache
+}
+
+func (s *secretFolderBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return secretFolderResourceType
+}
+
+// List returns the folders directly under a secret engine or a secret folder, secretsPageSize at a time.
+func (s *secretFolderBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	if !isSecretContainer(parentResourceID) {
+		return nil, "", nil, nil
+	}
+
+	bag, pageToken, err := getToken(pToken, secretFolderResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	folder, err := listKVFolder(ctx, s.client, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	keys, nextPageToken := folder.page(pageToken, true)
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, key := range keys {
+		fr, err := secretFolderResource(ctx, &client.APIResource{
+			ID:        client.QualifyID(folder.namespace, folder.childPath(key)),
+			Name:      folder.childPath(key),
+			MountType: folder.mount.Type,
+			MountPath: folder.mount.Path,
+		}, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, fr)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, rateLimitAnnotations(s.client), nil
+}
+
+func (s *secretFolderBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return secretCapabilityEntitlements(resource), "", nil, nil
+}
+
+func (s *secretFolderBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	rv, err := secretCapabilityGrants(ctx, s.client, s.cache, resource, true)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, "", rateLimitAnnotations(s.client), nil
+}
+
+func newSecretFolderBuilder(c *client.HCPClient, cache *syncCache) *secretFolderBuilder {
+	return &secretFolderBuilder{
+		resourceType: secretFolderResourceType,
+		client:       c,
+		cache:        cache,
+	}
+}
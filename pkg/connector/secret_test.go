@@ -0,0 +1,220 @@
+package connector
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretGrantsToPolicies(t *testing.T) {
+	vault := newFakeVault()
+	vault.policies["root"] = ""
+	vault.policies["app"] = `path "kv/data/*" { capabilities = ["read", "update"] }`
+	vault.policies["no-payroll"] = `path "kv/data/*" { capabilities = ["read"] }
+path "kv/data/payroll" { capabilities = ["deny"] }`
+	vault.policies["team"] = `path "kv/data/+/db" { capabilities = ["read"] }
+path "kv/data/team/db" { capabilities = ["update"] }`
+
+	c := newTestConnector(t, vault)
+	s := newSecretBuilder(c.client, c.cache)
+	capabilities := func(secretID string) map[string][]string {
+		grants, _, _, err := s.Grants(ctxTest, &v2.Resource{
+			Id: &v2.ResourceId{ResourceType: secretResourceType.Id, Resource: secretID},
+		}, &pagination.Token{})
+		require.Nil(t, err)
+
+		rv := map[string][]string{}
+		for _, g := range grants {
+			require.Equal(t, policyResourceType.Id, g.Principal.Id.ResourceType)
+
+			// The grants are expanded to the holders of the policy.
+			expandable := &v2.GrantExpandable{}
+			annos := annotations.Annotations(g.Annotations)
+			ok, err := annos.Pick(expandable)
+			require.Nil(t, err)
+			require.True(t, ok)
+			require.Equal(t, []string{ent.NewEntitlementID(&v2.Resource{Id: g.Principal.Id}, assignedEntitlement)}, expandable.EntitlementIds)
+
+			parts := strings.Split(g.Entitlement.Id, ":")
+			rv[g.Principal.Id.Resource] = append(rv[g.Principal.Id.Resource], parts[len(parts)-1])
+		}
+
+		return rv
+	}
+
+	payroll := capabilities("kv/payroll")
+	require.Equal(t, []string{"read", "update"}, payroll["app"])
+	require.Equal(t, secretCapabilities, payroll["root"])
+	// A deny on the exact path of the secret wins over the glob of the same policy.
+	require.NotContains(t, payroll, "no-payroll")
+	require.NotContains(t, payroll, "team")
+	require.NotContains(t, payroll, "default")
+
+	// The exact path wins over the + segment pattern.
+	db := capabilities("kv/team/db")
+	require.Equal(t, []string{"update"}, db["team"])
+	require.Equal(t, []string{"read"}, db["no-payroll"])
+	require.Equal(t, []string{"read"}, capabilities("kv/other/db")["team"])
+
+	// Each policy is read once for the whole sync.
+	require.Equal(t, 1, vault.requestCount("GET", "sys/policies/acl/app"))
+	require.Equal(t, 1, vault.requestCount("GET", "sys/policy"))
+}
+
+func TestSecretEntitlementsGrantableToPolicies(t *testing.T) {
+	for _, entitlement := range secretCapabilityEntitlements(&v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: secretResourceType.Id, Resource: "kv/app"},
+		DisplayName: "kv/app",
+	}) {
+		require.Equal(t, []*v2.ResourceType{policyResourceType}, entitlement.GrantableTo)
+	}
+}
+
+// walkSecrets lists every secret engine of the root namespace and descends into every folder the way the baton
+// syncer does, returning the secrets by ID and the parent each was listed under.
+func walkSecrets(t *testing.T, c *Connector) map[string]string {
+	secrets := map[string]string{}
+	folders := newSecretFolderBuilder(c.client, c.cache)
+	secretList := newSecretBuilder(c.client, c.cache)
+	var walk func(parent *v2.ResourceId)
+	walk = func(parent *v2.ResourceId) {
+		for _, secret := range allResources(t, secretList, parent) {
+			secrets[secret.Id.Resource] = secret.ParentResourceId.Resource
+		}
+		for _, folder := range allResources(t, folders, parent) {
+			walk(folder.Id)
+		}
+	}
+
+	for _, engine := range allResources(t, newSecretEngineBuilder(c.client), rootNamespaceResourceID) {
+		walk(engine.Id)
+	}
+
+	return secrets
+}
+
+func TestSecretsListEveryKVMountRecursively(t *testing.T) {
+	vault := newFakeVault()
+	vault.mounts["secret"] = client.SecretsMount{Type: "kv"}
+	vault.mounts["sys-like"] = client.SecretsMount{Type: "transit"}
+	vault.secrets["kv/app/db"] = &client.SecretMetadata{}
+	vault.secrets["kv/app/team/api"] = &client.SecretMetadata{}
+	vault.secrets["secret/legacy/token"] = &client.SecretMetadata{}
+	for i := 0; i < secretsPageSize+20; i++ {
+		vault.secrets[fmt.Sprintf("kv/bulk/s%03d", i)] = &client.SecretMetadata{}
+	}
+
+	c := newTestConnector(t, vault)
+	secrets := walkSecrets(t, c)
+	require.Len(t, secrets, secretsPageSize+23)
+	require.Equal(t, "kv/app/team", secrets["kv/app/team/api"])
+	require.Equal(t, "secret/legacy", secrets["secret/legacy/token"])
+	require.Equal(t, 1, vault.requestCount("GET", "kv/metadata/bulk/s000"))
+	require.Equal(t, 1, vault.requestCount("GET", "kv/metadata/bulk/s119"))
+
+	// The secrets of a large folder are listed a page at a time, reading the metadata of that page only.
+	vault = newFakeVault()
+	for i := 0; i < secretsPageSize+20; i++ {
+		vault.secrets[fmt.Sprintf("kv/bulk/s%03d", i)] = &client.SecretMetadata{}
+	}
+	c = newTestConnector(t, vault)
+	bulk := &v2.ResourceId{ResourceType: secretFolderResourceType.Id, Resource: "kv/bulk"}
+	page, next, _, err := newSecretBuilder(c.client, c.cache).List(ctxTest, bulk, &pagination.Token{})
+	require.Nil(t, err)
+	require.Len(t, page, secretsPageSize)
+	require.NotEmpty(t, next)
+	require.Equal(t, 0, vault.requestCount("GET", "kv/metadata/bulk/s119"))
+
+	page, next, _, err = newSecretBuilder(c.client, c.cache).List(ctxTest, bulk, &pagination.Token{Token: next})
+	require.Nil(t, err)
+	require.Len(t, page, 20)
+	require.Empty(t, next)
+	require.Equal(t, 1, vault.requestCount("GET", "kv/metadata/bulk/s119"))
+}
+
+func TestSecretProfileRecordsMetadata(t *testing.T) {
+	vault := newFakeVault()
+	vault.secrets["kv/db"] = &client.SecretMetadata{
+		CreatedTime:        "2026-01-02T03:04:05Z",
+		UpdatedTime:        "2026-03-02T03:04:05Z",
+		CurrentVersion:     3,
+		OldestVersion:      2,
+		MaxVersions:        10,
+		DeleteVersionAfter: "720h",
+		CustomMetadata:     map[string]string{"owner": "payments"},
+		Versions: map[string]client.SecretVersion{
+			"2": {CreatedTime: "2026-02-02T03:04:05Z", DeletionTime: "2026-02-03T03:04:05Z"},
+			"3": {CreatedTime: "2026-03-02T03:04:05Z", Destroyed: true},
+		},
+	}
+
+	c := newTestConnector(t, vault)
+	secrets := allResources(t, newSecretBuilder(c.client, c.cache), &v2.ResourceId{ResourceType: secretEngineResourceType.Id, Resource: "kv"})
+	require.Len(t, secrets, 1)
+
+	trait, err := rs.GetAppTrait(secrets[0])
+	require.Nil(t, err)
+	profile := trait.Profile.AsMap()
+	require.Equal(t, "2026-01-02T03:04:05Z", profile["created_time"])
+	require.Equal(t, "2026-03-02T03:04:05Z", profile["updated_time"])
+	require.EqualValues(t, 3, profile["current_version"])
+	require.EqualValues(t, 2, profile["oldest_version"])
+	require.EqualValues(t, 10, profile["max_versions"])
+	require.Equal(t, "720h", profile["delete_version_after"])
+	require.Equal(t, map[string]interface{}{"owner": "payments"}, profile["custom_metadata"])
+	require.Equal(t, map[string]interface{}{
+		"2": map[string]interface{}{"created_time": "2026-02-02T03:04:05Z", "deletion_time": "2026-02-03T03:04:05Z", "deleted": true, "destroyed": false},
+		"3": map[string]interface{}{"created_time": "2026-03-02T03:04:05Z", "deletion_time": "", "deleted": false, "destroyed": true},
+	}, profile["versions"])
+
+	// Secret values are never read.
+	for request := range vault.requests {
+		require.NotContains(t, request, "kv/data/")
+	}
+}
+
+func TestSecretTree(t *testing.T) {
+	vault := newFakeVault()
+	vault.mounts["secret"] = client.SecretsMount{Type: "kv"}
+	vault.secrets["kv/app1/db"] = &client.SecretMetadata{}
+	vault.secrets["secret/app1/db"] = &client.SecretMetadata{}
+	for i := 0; i < secretsPageSize+1; i++ {
+		vault.secrets[fmt.Sprintf("kv/teams/t%03d/key", i)] = &client.SecretMetadata{}
+	}
+
+	c := newTestConnector(t, vault)
+	engines := allResources(t, newSecretEngineBuilder(c.client), rootNamespaceResourceID)
+	require.Len(t, engines, 2)
+	for _, engine := range engines {
+		require.Equal(t, rootNamespaceResourceID.Resource, engine.ParentResourceId.Resource)
+
+		var children []string
+		annos := annotations.Annotations(engine.Annotations)
+		for _, a := range annos {
+			child := &v2.ChildResourceType{}
+			if a.MessageIs(child) {
+				require.Nil(t, a.UnmarshalTo(child))
+				children = append(children, child.ResourceTypeId)
+			}
+		}
+		require.ElementsMatch(t, []string{secretFolderResourceType.Id, secretResourceType.Id}, children)
+	}
+
+	// The same path on two mounts is two secrets, each under the folder of its mount.
+	secrets := walkSecrets(t, c)
+	require.Equal(t, "kv/app1", secrets["kv/app1/db"])
+	require.Equal(t, "secret/app1", secrets["secret/app1/db"])
+
+	// Folders are paged like secrets.
+	folders := allResources(t, newSecretFolderBuilder(c.client, c.cache), &v2.ResourceId{ResourceType: secretFolderResourceType.Id, Resource: "kv/teams"})
+	require.Len(t, folders, secretsPageSize+1)
+	require.Equal(t, "kv/teams", folders[0].ParentResourceId.Resource)
+}
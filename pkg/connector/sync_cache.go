@@ -0,0 +1,206 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+)
+
+// syncCache keeps what several resource syncers read from Vault during a sync: the policies of every namespace
+// and their parsed rules, the identity groups and entities of every namespace and the role of every AppRole role ID.
+// It is emptied when the client starts a new sync, so a connector running as a service sees the changes made in
+// Vault between two syncs. It is filled around the response cache of the client, whose entries outlive a sync.
+type syncCache struct {
+	mu         sync.Mutex
+	generation uint64
+	// rules are the parsed rules of a policy, by qualified policy ID.
+	rules map[string][]client.ACLRule
+	// policies are the policy names of a namespace.
+	policies map[string][]string
+	// identities are the identity groups and entities of a namespace.
+	identities map[string]*namespaceIdentity
+	// roles map, per AppRole mount accessor, the role ID of every role to the role name.
+	roles map[string]map[string]string
+}
+
+// namespaceIdentity is the identity groups and entities of a namespace.
+type namespaceIdentity struct {
+	groupIds  []string
+	groups    map[string]*client.GroupData
+	entityIds []string
+	entities  map[string]*client.EntityData
+	// aliases map the mount accessor and name of every entity alias to the entity.
+	aliases map[string]string
+}
+
+func newSyncCache() *syncCache {
+	return &syncCache{}
+}
+
+// refresh empties the cache when a sync started since it was filled. The lock must be held.
+func (s *syncCache) refresh(c *client.HCPClient) {
+	generation := c.SyncGeneration()
+	if s.rules != nil && s.generation == generation {
+		return
+	}
+
+	s.generation = generation
+	s.rules = make(map[string][]client.ACLRule)
+	s.policies = make(map[string][]string)
+	s.identities = make(map[string]*namespaceIdentity)
+	s.roles = make(map[string]map[string]string)
+}
+
+// approleRoleName returns the name of the role of an AppRole mount whose role ID, the name of its entity aliases,
+// is roleID. It returns "" when no role has it. The role IDs of a mount are read once per sync, around the response
+// cache so a role ID set between two syncs is seen.
+func (s *syncCache) approleRoleName(ctx context.Context, c *client.HCPClient, mount client.AuthMount, roleID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refresh(c)
+	roleNames, ok := s.roles[mount.Accessor]
+	if !ok {
+		c = c.Uncached()
+		roles, err := c.GetRoles(ctx, mount.Path)
+		if err != nil {
+			return "", err
+		}
+
+		roleNames = make(map[string]string, len(roles.Data.Keys))
+		for _, role := range roles.Data.Keys {
+			id, err := c.GetRoleID(ctx, mount.Path, role)
+			if err != nil {
+				return "", err
+			}
+			roleNames[id] = role
+		}
+
+		s.roles[mount.Accessor] = roleNames
+	}
+
+	return roleNames[roleID], nil
+}
+
+// aliasEntity returns the ID of the entity owning an alias, "" when the alias has no entity yet.
+func (s *syncCache) aliasEntity(ctx context.Context, c *client.HCPClient, namespace, mountAccessor, aliasName string) (string, error) {
+	identity, err := s.identity(ctx, c, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	return identity.aliases[mountQualifiedID(mountAccessor, aliasName)], nil
+}
+
+// identity returns the identity groups and entities of a namespace, reading them once per sync since every policy
+// and role resolves the groups and entities of the same principals.
+func (s *syncCache) identity(ctx context.Context, c *client.HCPClient, namespace string) (*namespaceIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refresh(c)
+	if identity, ok := s.identities[namespace]; ok {
+		return identity, nil
+	}
+
+	cli := c.Namespace(namespace).Uncached()
+	groups, _, err := cli.ListAllGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &namespaceIdentity{
+		groupIds: groups.Data.Keys,
+		groups:   make(map[string]*client.GroupData, len(groups.Data.Keys)),
+		aliases:  make(map[string]string),
+	}
+	for _, groupId := range groups.Data.Keys {
+		group, err := cli.GetGroup(ctx, groupId)
+		if err != nil {
+			return nil, err
+		}
+		identity.groups[groupId] = &group.Data
+	}
+
+	entities, _, err := cli.ListAllEntities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.entityIds = entities.Data.Keys
+	identity.entities = make(map[string]*client.EntityData, len(entities.Data.Keys))
+	for _, entityId := range entities.Data.Keys {
+		entity, err := cli.GetEntity(ctx, entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		identity.entities[entityId] = &entity.Data
+		for _, alias := range entity.Data.Aliases {
+			identity.aliases[mountQualifiedID(alias.MountAccessor, alias.Name)] = entityId
+		}
+	}
+
+	s.identities[namespace] = identity
+	return identity, nil
+}
+
+// policyRules returns the parsed rules of a policy, reading the policy once per sync.
+// The root policy has no rules since it allows everything.
+func (s *syncCache) policyRules(ctx context.Context, c *client.HCPClient, namespace, name string) ([]client.ACLRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refresh(c)
+	return s.policyRulesLocked(ctx, c, namespace, name)
+}
+
+func (s *syncCache) policyRulesLocked(ctx context.Context, c *client.HCPClient, namespace, name string) ([]client.ACLRule, error) {
+	if name == client.RootPolicy {
+		return nil, nil
+	}
+
+	key := client.QualifyID(namespace, name)
+	if rules, ok := s.rules[key]; ok {
+		return rules, nil
+	}
+
+	policy, err := c.Namespace(namespace).Uncached().GetACLPolicy(ctx, name)
+	// Vault lets principals name policies that do not exist, they allow nothing.
+	if client.IsNotFound(err) {
+		s.rules[key] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := client.ParseACLPolicy(policy.Data.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("hcp-connector: policy %s: %w", name, err)
+	}
+
+	s.rules[key] = rules
+	return rules, nil
+}
+
+// namespacePolicies returns the names of the policies of a namespace, listing them once per sync.
+func (s *syncCache) namespacePolicies(ctx context.Context, c *client.HCPClient, namespace string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refresh(c)
+	if policies, ok := s.policies[namespace]; ok {
+		return policies, nil
+	}
+
+	policies, err := c.Namespace(namespace).Uncached().GetPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.policies[namespace] = policies.Data.Policies
+	return policies.Data.Policies, nil
+}
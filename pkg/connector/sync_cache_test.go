@@ -0,0 +1,70 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncCacheRefreshesEverySync(t *testing.T) {
+	vault := newFakeVault()
+	c := newTestConnector(t, vault)
+
+	entityId, err := c.cache.aliasEntity(ctxTest, c.client, "", "auth_userpass_1", "alice")
+	require.Nil(t, err)
+	require.Equal(t, "", entityId)
+
+	vault.mu.Lock()
+	vault.entities["e-alice"] = &client.EntityData{
+		ID:      "e-alice",
+		Aliases: []client.AliasData{{Name: "alice", MountAccessor: "auth_userpass_1"}},
+	}
+	vault.mu.Unlock()
+
+	// The entities are read once per sync.
+	entityId, err = c.cache.aliasEntity(ctxTest, c.client, "", "auth_userpass_1", "alice")
+	require.Nil(t, err)
+	require.Equal(t, "", entityId)
+
+	c.client.StartSync()
+	entityId, err = c.cache.aliasEntity(ctxTest, c.client, "", "auth_userpass_1", "alice")
+	require.Nil(t, err)
+	require.Equal(t, "e-alice", entityId)
+}
+
+func TestValidateStartsSync(t *testing.T) {
+	vault := newFakeVault()
+	c := newTestConnector(t, vault)
+
+	generation := c.client.SyncGeneration()
+	_, err := c.Validate(ctxTest)
+	require.Nil(t, err)
+	require.Equal(t, generation+1, c.client.SyncGeneration())
+
+	// Listing namespaces leaves the sync alone, whatever order the resource types are synced in.
+	_, _, _, err = newNamespaceBuilder(c.client).List(ctxTest, nil, &pagination.Token{})
+	require.Nil(t, err)
+	require.Equal(t, generation+1, c.client.SyncGeneration())
+}
+
+func TestSyncCacheReadsEntitiesAgainEverySync(t *testing.T) {
+	vault := newFakeVault()
+	vault.entities["e-alice"] = &client.EntityData{ID: "e-alice"}
+	c := newTestConnector(t, vault)
+
+	entityId, err := c.cache.aliasEntity(ctxTest, c.client, "", "auth_userpass_1", "alice")
+	require.Nil(t, err)
+	require.Equal(t, "", entityId)
+
+	// alice logs in between two syncs, the entity read during the previous sync gains her alias.
+	vault.mu.Lock()
+	vault.entities["e-alice"].Aliases = []client.AliasData{{Name: "alice", MountAccessor: "auth_userpass_1"}}
+	vault.mu.Unlock()
+
+	c.client.StartSync()
+	entityId, err = c.cache.aliasEntity(ctxTest, c.client, "", "auth_userpass_1", "alice")
+	require.Nil(t, err)
+	require.Equal(t, "e-alice", entityId)
+}
@@ -26,12 +26,20 @@ func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		rv  []*v2.Resource
 	)
 
-	bag, _, err := getToken(pToken, userResourceType)
+	// Resources are listed per namespace, under the namespace resource.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	namespace := namespacePath(parentResourceID.Resource)
+	cli := u.client.Namespace(namespace)
+	bag, pageToken, err := getToken(pToken, userResourceType)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	users, nextPageToken, err := u.client.ListAllUsers(ctx)
+	// Every page holds the users of one userpass mount.
+	mount, users, nextPageToken, err := cli.ListAllUsers(ctx, pageToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -43,10 +51,12 @@ func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 
 	for _, user := range users.Data.Keys {
 		ur, err := userResource(ctx, &client.APIResource{
-			ID:        user,
-			Name:      user,
-			MountType: users.MountType,
-		}, nil)
+			ID:            client.QualifyID(namespace, mountQualifiedID(mount.Path, user)),
+			Name:          user,
+			MountType:     mount.Type,
+			MountPath:     mount.Path,
+			MountAccessor: mount.Accessor,
+		}, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -58,7 +68,7 @@ func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		return nil, "", nil, err
 	}
 
-	return rv, nextPageToken, nil, nil
+	return rv, nextPageToken, rateLimitAnnotations(u.client), nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -0,0 +1,32 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsersListEveryUserpassMount(t *testing.T) {
+	vault := newFakeVault()
+	vault.auth["userpass-contractors"] = client.AuthMount{Type: client.UserpassType, Accessor: "auth_userpass_2"}
+	vault.users["userpass"]["alice"] = &client.UserData{}
+	vault.users["userpass-contractors"] = map[string]*client.UserData{"alice": {}, "bob": {}}
+
+	c := newTestConnector(t, vault)
+	users := map[string]string{}
+	for _, user := range allResources(t, newUserBuilder(c.client), rootNamespaceResourceID) {
+		trait, err := rs.GetUserTrait(user)
+		require.Nil(t, err)
+		accessor, _ := rs.GetProfileStringValue(trait.Profile, "mount_accessor")
+		users[user.Id.Resource] = accessor
+	}
+
+	// Users of the same name on two mounts no longer collide.
+	require.Equal(t, map[string]string{
+		"userpass/alice":             "auth_userpass_1",
+		"userpass-contractors/alice": "auth_userpass_2",
+		"userpass-contractors/bob":   "auth_userpass_2",
+	}, users)
+}
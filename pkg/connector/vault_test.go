@@ -0,0 +1,332 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-hashicorp-vault/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault is an in-memory Vault serving the endpoints the connector reads and writes in the root namespace.
+type fakeVault struct {
+	mu       sync.Mutex
+	auth     map[string]client.AuthMount
+	mounts   map[string]client.SecretsMount
+	policies map[string]string
+	// users and roles are keyed by mount path, then by name.
+	users   map[string]map[string]*client.UserData
+	roles   map[string]map[string]*client.RoleData
+	roleIDs map[string]map[string]string
+	// secrets are the secrets of the kv mounts by path, mount included, ex. "kv/app/db". Only the metadata of
+	// kv v2 secrets is served, secret values never are.
+	secrets  map[string]*client.SecretMetadata
+	groups   map[string]*client.GroupData
+	entities map[string]*client.EntityData
+	// namespaces are the child namespaces of the root namespace, ex. "bu1/".
+	namespaces []string
+	// requests counts the requests by method and path, ex. "GET auth/approle/role/app/role-id".
+	requests map[string]int
+	// afterWrite is called, without the lock held, once a write to path is applied.
+	afterWrite func(path string)
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{
+		auth: map[string]client.AuthMount{
+			"userpass": {Type: client.UserpassType, Accessor: "auth_userpass_1"},
+			"approle":  {Type: client.ApproleType, Accessor: "auth_approle_1"},
+		},
+		mounts: map[string]client.SecretsMount{
+			"kv": {Type: "kv", Options: map[string]string{"version": "2"}},
+		},
+		policies: map[string]string{"default": ""},
+		users:    map[string]map[string]*client.UserData{"userpass": {}},
+		roles:    map[string]map[string]*client.RoleData{"approle": {}},
+		roleIDs:  map[string]map[string]string{"approle": {}},
+		secrets:  map[string]*client.SecretMetadata{},
+		groups:   map[string]*client.GroupData{},
+		entities: map[string]*client.EntityData{},
+		requests: map[string]int{},
+	}
+}
+
+// newTestConnector returns a writable connector talking to vault.
+func newTestConnector(t *testing.T, vault *fakeVault, configure ...func(*client.HCPClient)) *Connector {
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+
+	cli := client.NewClient()
+	require.Nil(t, cli.WithAddress(server.URL))
+	cli.WithBearerToken("token")
+	cli.WithReadOnly(false)
+	cli.WithMaxRetries(0)
+	for _, fn := range configure {
+		fn(cli)
+	}
+
+	c, err := New(ctxTest, server.URL, cli)
+	require.Nil(t, err)
+
+	return c
+}
+
+// allResources returns every page of the resources a builder lists under parent.
+func allResources(t *testing.T, b interface {
+	List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error)
+}, parent *v2.ResourceId) []*v2.Resource {
+	var rv []*v2.Resource
+	token := ""
+	for {
+		resources, next, _, err := b.List(ctxTest, parent, &pagination.Token{Token: token})
+		require.Nil(t, err)
+		rv = append(rv, resources...)
+		if next == "" {
+			return rv
+		}
+		token = next
+	}
+}
+
+// requestCount returns how many requests were made with method on path.
+func (f *fakeVault) requestCount(method, path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests[method+" "+path]
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if r.Method == http.MethodPost && f.afterWrite != nil {
+		defer f.afterWrite(path)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests[r.Method+" "+path]++
+	var body map[string]json.RawMessage
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	segments := strings.Split(path, "/")
+	switch {
+	case path == "auth/token/lookup-self":
+		writeData(w, map[string]any{"ttl": 0})
+	case path == "sys/health", path == "sys/seal-status":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"initialized": true, "sealed": false})
+	case path == "sys/capabilities-self":
+		var paths []string
+		decodeField(body, "paths", &paths)
+		capabilities := map[string][]string{}
+		for _, p := range paths {
+			capabilities[p] = []string{"root"}
+		}
+		writeData(w, capabilities)
+	case path == "sys/auth":
+		writeData(w, withTrailingSlash(f.auth))
+	case path == "sys/namespaces":
+		writeData(w, map[string]any{"keys": f.namespaces})
+	case path == "sys/mounts":
+		writeData(w, withTrailingSlash(f.mounts))
+	case path == "sys/policy":
+		writeData(w, map[string]any{"policies": sortedKeys(f.policies)})
+	case strings.HasPrefix(path, "sys/policies/acl/"):
+		name := strings.TrimPrefix(path, "sys/policies/acl/")
+		if r.Method == http.MethodPost {
+			var policy string
+			decodeField(body, "policy", &policy)
+			f.policies[name] = policy
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		policy, ok := f.policies[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeData(w, client.ACLPolicyData{Name: name, Policy: policy})
+	case path == "identity/group/id":
+		writeData(w, map[string]any{"keys": sortedKeys(f.groups)})
+	case strings.HasPrefix(path, "identity/group/id/"):
+		group, ok := f.groups[strings.TrimPrefix(path, "identity/group/id/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			decodeField(body, "policies", &group.Policies)
+			decodeField(body, "member_entity_ids", &group.MemberEntityIDs)
+			decodeField(body, "member_group_ids", &group.MemberGroupIDs)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeData(w, group)
+	case path == "identity/entity/id":
+		writeData(w, map[string]any{"keys": sortedKeys(f.entities)})
+	case strings.HasPrefix(path, "identity/entity/id/"):
+		entity, ok := f.entities[strings.TrimPrefix(path, "identity/entity/id/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			decodeField(body, "policies", &entity.Policies)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeData(w, entity)
+	case path == "identity/lookup/entity":
+		var name, accessor string
+		decodeField(body, "alias_name", &name)
+		decodeField(body, "alias_mount_accessor", &accessor)
+		for _, entity := range f.entities {
+			for _, alias := range entity.Aliases {
+				if alias.Name == name && alias.MountAccessor == accessor {
+					writeData(w, entity)
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) >= 3 && segments[0] == "auth" && segments[2] == "users":
+		f.serveUsers(w, r, segments[1], segments[3:], body)
+	case len(segments) >= 3 && segments[0] == "auth" && segments[2] == "role":
+		f.serveRoles(w, r, segments[1], segments[3:], body)
+	case f.mounts[segments[0]].Type == "kv":
+		f.serveKV(w, r, segments[0], segments[1:])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeVault) serveUsers(w http.ResponseWriter, r *http.Request, mount string, rest []string, body map[string]json.RawMessage) {
+	if len(rest) == 0 {
+		writeData(w, map[string]any{"keys": sortedKeys(f.users[mount])})
+		return
+	}
+
+	user, ok := f.users[mount][rest[0]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		decodeField(body, "token_policies", &user.TokenPolicies)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeData(w, user)
+}
+
+func (f *fakeVault) serveRoles(w http.ResponseWriter, r *http.Request, mount string, rest []string, body map[string]json.RawMessage) {
+	if len(rest) == 0 {
+		writeData(w, map[string]any{"keys": sortedKeys(f.roles[mount])})
+		return
+	}
+
+	role, ok := f.roles[mount][rest[0]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(rest) == 2 && rest[1] == "role-id":
+		writeData(w, client.RoleIDData{RoleID: f.roleIDs[mount][rest[0]]})
+	case r.Method == http.MethodPost:
+		decodeField(body, "token_policies", &role.TokenPolicies)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeData(w, role)
+	}
+}
+
+func (f *fakeVault) serveKV(w http.ResponseWriter, r *http.Request, mount string, rest []string) {
+	if f.mounts[mount].Options["version"] == "2" {
+		if len(rest) == 0 || rest[0] != "metadata" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		rest = rest[1:]
+	}
+
+	path := strings.Join(append([]string{mount}, rest...), "/")
+	if r.Method != "LIST" {
+		metadata, ok := f.secrets[path]
+		if !ok || f.mounts[mount].Options["version"] != "2" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		writeData(w, metadata)
+		return
+	}
+
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	children := map[string]bool{}
+	for secretPath := range f.secrets {
+		key, ok := strings.CutPrefix(secretPath, prefix)
+		if !ok {
+			continue
+		}
+		if folder, _, nested := strings.Cut(key, "/"); nested {
+			key = folder + "/"
+		}
+		children[key] = true
+	}
+
+	// Vault answers a LIST on a path without any key with a 404.
+	if len(children) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeData(w, map[string]any{"keys": sortedKeys(children)})
+}
+
+func writeData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func decodeField(body map[string]json.RawMessage, field string, v any) {
+	if raw, ok := body[field]; ok {
+		_ = json.Unmarshal(raw, v)
+	}
+}
+
+func withTrailingSlash[T any](values map[string]T) map[string]T {
+	rv := make(map[string]T, len(values))
+	for key, value := range values {
+		rv[key+"/"] = value
+	}
+
+	return rv
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+	return keys
+}